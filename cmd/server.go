@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -14,10 +16,12 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/partyscript/bledger/internal/cache"
 	"github.com/partyscript/bledger/internal/cache/redis"
+	"github.com/partyscript/bledger/internal/cli"
 	"github.com/partyscript/bledger/internal/common"
 	"github.com/partyscript/bledger/internal/config"
 	"github.com/partyscript/bledger/internal/controller"
 	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/exchange"
 	"github.com/partyscript/bledger/internal/router"
 
 	"github.com/joho/godotenv"
@@ -73,14 +77,33 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Create the fx rate provider used for currency conversions
+	fx, err := exchange.NewProvider(cfg.FX.Provider, cfg.FX.Rates, cfg.FX.RatesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Create a new instance of a controller manager
 	cm := controller.NewControllerManager(
 		sugaredLogger,
 		cfg,
 		cacher,
 		dbManager,
+		fx,
 	)
 
+	// Run a maintenance subcommand, e.g. `verify --repair`, instead of serving
+	if len(os.Args) > 1 {
+		zapLogger.Sync()
+		os.Exit(cli.Run(cm, os.Args[1:], os.Stdout))
+	}
+
+	// Expire stale pending transactions and holds in the background
+	go cm.Expiry.Run(context.Background())
+
+	// Snapshot account balances in the background so balance history stays fast
+	go cm.Snapshots.Run(context.Background())
+
 	ic := common.NewIdempotencyConfig(
 		[]string{"GET", "HEAD", "OPTIONS", "TRACE"},
 		[]string{},
@@ -5,6 +5,7 @@ import { Account } from "./interfaces";
 export async function accounts() {
   await testCreateAccount();
   await testGetAccount();
+  await testCreateMultiCurrencyAccount();
 }
 
 export async function testGetAccount() {
@@ -43,8 +44,8 @@ export async function testGetAccount() {
   assert(data !== undefined);
   assert(parsedData2.description === body.description);
   assert(parsedData2.name === body.name);
-  assert(parsedData2.balance.amount === 0);
-  assert(parsedData2.balance.currency === body.currency);
+  assert(parsedData2.balance[0].amount === "0");
+  assert(parsedData2.balance[0].currency === body.currency);
 }
 
 export async function testCreateAccount(): Promise<Account> {
@@ -72,8 +73,37 @@ export async function testCreateAccount(): Promise<Account> {
   assert(data !== undefined);
   assert(parsedData.description === body.description);
   assert(parsedData.name === body.name);
-  assert(parsedData.balance.amount === 0);
-  assert(parsedData.balance.currency === body.currency);
+  assert(parsedData.balance[0].amount === "0");
+  assert(parsedData.balance[0].currency === body.currency);
 
   return parsedData;
 }
+
+export async function testCreateMultiCurrencyAccount() {
+  const body = {
+    description: "test account",
+    name: "test account",
+    currencies: ["USD", "EUR", "GBP"],
+  };
+
+  const headers = {
+    Accept: "*/*",
+    "Content-Type": "application/json",
+  };
+
+  const response = await fetch("http://localhost:8080/v1/accounts", {
+    method: "POST",
+    body: JSON.stringify(body),
+    headers: headers,
+  });
+
+  const data = await response.text();
+  const parsedData = JSON.parse(data) as Account;
+
+  assert(response.status === 201 || response.status === 200);
+  assert(parsedData.balance.length === body.currencies.length);
+  parsedData.balance.forEach((money, i) => {
+    assert(money.amount === "0");
+    assert(money.currency === body.currencies[i]);
+  });
+}
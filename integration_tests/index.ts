@@ -1,9 +1,11 @@
 import { accounts } from "./account";
 import { transactions } from "./transactions";
+import { transfers } from "./transfers";
 
 async function main() {
   await transactions();
   await accounts();
+  await transfers();
 }
 
 main();
@@ -1,5 +1,5 @@
 export interface Money {
-  amount: number;
+  amount: string;
   currency: string;
 }
 
@@ -11,11 +11,30 @@ export interface Transaction {
   memo: string;
   state: string;
   error_reason?: string;
+  reverses_transaction_id?: string;
+  reversed_by?: string;
 }
 
 export interface Account {
   id: string;
   name: string;
   description: string;
-  balance: Money;
+  balance: Money[];
+}
+
+export interface Transfer {
+  id: string;
+  money: Money;
+  memo: string;
+  from_account_id: string;
+  to_account_id: string;
+  debit_transaction_id: string;
+  credit_transaction_id: string;
+}
+
+export interface TransactionBatch {
+  mode: string;
+  succeeded: number;
+  failed: number;
+  results: { index: number; transaction?: Transaction; error?: string }[];
 }
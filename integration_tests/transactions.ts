@@ -2,15 +2,62 @@ import { assert } from "console";
 import fetch from "node-fetch";
 import { testCreateAccount } from "./account";
 import { gen_key } from "./helpers";
-import { Account, Transaction } from "./interfaces";
+import { Account, Transaction, TransactionBatch } from "./interfaces";
 
 export async function transactions() {
   await testFailedTransaction();
   await testPendingTransaction();
   await testImmediateTransaction();
+  await testReverseTransaction();
   await testPendingTransactionExecution();
   await testInsufficientBalance();
   await testAccountBalanceChangedAfterTransaction();
+  await testTransactionBatch();
+}
+
+export async function testTransactionBatch() {
+  const acct = await testCreateAccount();
+
+  const item = (direction: string, amount: string) => ({
+    money: { amount, currency: "USD" },
+    memo: "batch tx",
+    direction,
+    account_id: acct.id,
+  });
+  const items = [item("CREDIT", "10"), item("DEBIT", "4"), item("DEBIT", "7")];
+
+  const submit = (mode: string) =>
+    fetch("http://localhost:8080/v1/transactions/batch", {
+      method: "POST",
+      body: JSON.stringify({ mode, items }),
+      headers: { Accept: "*/*", "Content-Type": "application/json" },
+    });
+
+  // The last debit overdraws, so nothing is committed
+  const atomic = await submit("ATOMIC");
+  assert(atomic.status === 400);
+
+  const afterAtomic = await fetch(
+    `http://localhost:8080/v1/accounts/${acct.id}`
+  );
+  const acctAfterAtomic = (await afterAtomic.json()) as Account;
+  assert(acctAfterAtomic.balance[0].amount === "0");
+
+  // Best effort commits the first two and reports the third
+  const bestEffort = await submit("BEST_EFFORT");
+  const batch = (await bestEffort.json()) as TransactionBatch;
+
+  assert(bestEffort.status === 201);
+  assert(batch.succeeded === 2);
+  assert(batch.failed === 1);
+  assert(batch.results[0].transaction?.state === "COMPLETED");
+  assert(batch.results[2].error === "insufficient funds");
+
+  const afterBestEffort = await fetch(
+    `http://localhost:8080/v1/accounts/${acct.id}`
+  );
+  const acctAfterBestEffort = (await afterBestEffort.json()) as Account;
+  assert(acctAfterBestEffort.balance[0].amount === "6");
 }
 
 export async function testAccountBalanceChangedAfterTransaction() {
@@ -18,7 +65,7 @@ export async function testAccountBalanceChangedAfterTransaction() {
 
   const body = {
     money: {
-      amount: 10,
+      amount: "10",
       currency: "USD",
     },
     memo: "test tx",
@@ -64,7 +111,7 @@ export async function testAccountBalanceChangedAfterTransaction() {
   assert(parsedData.direction === body.direction);
   assert(parsedData.state === "COMPLETED");
   assert(parsedData.memo === body.memo);
-  assert(parsedData2.balance.amount === body.money.amount);
+  assert(parsedData2.balance[0].amount === body.money.amount);
 }
 
 export async function testInsufficientBalance() {
@@ -72,7 +119,7 @@ export async function testInsufficientBalance() {
 
   const body = {
     money: {
-      amount: 10,
+      amount: "10",
       currency: "USD",
     },
     memo: "test tx",
@@ -108,7 +155,7 @@ export async function testInsufficientBalance() {
 }
 
 export async function testReverseTransaction() {
-  const { account, transaction } = await testPendingTransaction();
+  const { account, transaction } = await testImmediateTransaction();
 
   const headers = {
     Accept: "*/*",
@@ -126,13 +173,38 @@ export async function testReverseTransaction() {
   const data = await response.text();
   const parsedData = JSON.parse(data) as Transaction;
 
-  assert(response.status === 201 || response.status === 200);
-  assert(data !== undefined);
+  assert(response.status === 201);
+  assert(parsedData.id !== transaction.id);
   assert(parsedData.account_id === account.id);
   assert(parsedData.money.amount === transaction.money.amount);
   assert(parsedData.money.currency === transaction.money.currency);
-  assert(parsedData.direction === transaction.direction);
-  assert(parsedData.state === "REVERSED");
+  assert(parsedData.direction === "DEBIT");
+  assert(parsedData.state === "COMPLETED");
+  assert(parsedData.reverses_transaction_id === transaction.id);
+
+  const original = await fetch(
+    `http://localhost:8080/v1/transactions/${transaction.id}`,
+    {
+      method: "GET",
+      headers: headers,
+    }
+  );
+
+  const parsedOriginal = JSON.parse(await original.text()) as Transaction;
+
+  assert(parsedOriginal.state === "COMPLETED");
+  assert(parsedOriginal.direction === transaction.direction);
+  assert(parsedOriginal.reversed_by === parsedData.id);
+
+  const again = await fetch(
+    `http://localhost:8080/v1/transactions/${transaction.id}`,
+    {
+      method: "DELETE",
+      headers: headers,
+    }
+  );
+
+  assert(again.status === 409);
 }
 
 export async function testPendingTransactionExecution() {
@@ -171,7 +243,7 @@ export async function testPendingTransaction(): Promise<{
 
   const body = {
     money: {
-      amount: 10,
+      amount: "10",
       currency: "USD",
     },
     memo: "test tx",
@@ -212,7 +284,7 @@ export async function testImmediateTransaction() {
 
   const body = {
     money: {
-      amount: 10,
+      amount: "10",
       currency: "USD",
     },
     memo: "test tx",
@@ -247,12 +319,14 @@ export async function testImmediateTransaction() {
   assert(parsedData.money.currency === body.money.currency);
   assert(parsedData.direction === body.direction);
   assert(parsedData.state === "COMPLETED");
+
+  return { account: acct, transaction: parsedData };
 }
 
 export async function testFailedTransaction() {
   const body = {
     money: {
-      amount: 10,
+      amount: "10",
       currency: "USD",
     },
     memo: "test tx",
@@ -0,0 +1,120 @@
+import { assert } from "console";
+import fetch from "node-fetch";
+import { testCreateAccount } from "./account";
+import { Account, Transfer } from "./interfaces";
+
+export async function transfers() {
+  await testTransfer();
+  await testTransferInsufficientFunds();
+}
+
+async function fundAccount(acct: Account, amount: string) {
+  const body = {
+    money: {
+      amount: amount,
+      currency: "USD",
+    },
+    memo: "funding",
+    direction: "CREDIT",
+    account_id: acct.id,
+  };
+
+  const headers = {
+    Accept: "*/*",
+    "Content-Type": "application/json",
+  };
+
+  await fetch("http://localhost:8080/v1/transactions/immediate", {
+    method: "POST",
+    body: JSON.stringify(body),
+    headers: headers,
+  });
+}
+
+export async function testTransfer() {
+  const from = await testCreateAccount();
+  const to = await testCreateAccount();
+  await fundAccount(from, "10");
+
+  const body = {
+    money: {
+      amount: "7",
+      currency: "USD",
+    },
+    memo: "test transfer",
+    from_account_id: from.id,
+    to_account_id: to.id,
+  };
+
+  const headers = {
+    Accept: "*/*",
+    "Content-Type": "application/json",
+  };
+
+  const response = await fetch("http://localhost:8080/v1/transfers", {
+    method: "POST",
+    body: JSON.stringify(body),
+    headers: headers,
+  });
+
+  const data = await response.text();
+  const parsedData = JSON.parse(data) as Transfer;
+
+  const fromResponse = await fetch(
+    `http://localhost:8080/v1/accounts/${from.id}`,
+    {
+      method: "GET",
+      headers: headers,
+    }
+  );
+  const fromAcct = JSON.parse(await fromResponse.text()) as Account;
+
+  const toResponse = await fetch(`http://localhost:8080/v1/accounts/${to.id}`, {
+    method: "GET",
+    headers: headers,
+  });
+  const toAcct = JSON.parse(await toResponse.text()) as Account;
+
+  assert(response.status === 201 || response.status === 200);
+  assert(parsedData.from_account_id === from.id);
+  assert(parsedData.to_account_id === to.id);
+  assert(parsedData.debit_transaction_id !== "");
+  assert(parsedData.credit_transaction_id !== "");
+  assert(fromAcct.balance[0].amount === "3");
+  assert(toAcct.balance[0].amount === "7");
+}
+
+export async function testTransferInsufficientFunds() {
+  const from = await testCreateAccount();
+  const to = await testCreateAccount();
+
+  const body = {
+    money: {
+      amount: "10",
+      currency: "USD",
+    },
+    memo: "test transfer",
+    from_account_id: from.id,
+    to_account_id: to.id,
+  };
+
+  const headers = {
+    Accept: "*/*",
+    "Content-Type": "application/json",
+  };
+
+  const response = await fetch("http://localhost:8080/v1/transfers", {
+    method: "POST",
+    body: JSON.stringify(body),
+    headers: headers,
+  });
+
+  const toResponse = await fetch(`http://localhost:8080/v1/accounts/${to.id}`, {
+    method: "GET",
+    headers: headers,
+  });
+  const toAcct = JSON.parse(await toResponse.text()) as Account;
+
+  assert(response.status === 400);
+  assert(toAcct.balance[0].amount === "0");
+}
@@ -0,0 +1,319 @@
+// Package camt writes account statements as ISO 20022 camt.053.001.02 bank to
+// customer statements. A document holds one statement per currency the account
+// holds, and entries are written as they are added, so it never has to fit in memory.
+package camt
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/partyscript/bledger/internal/statement"
+)
+
+// Namespace is the XML namespace of camt.053.001.02 documents
+const Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ContentType is the HTTP content type of a camt.053 document
+const ContentType = "application/xml; charset=utf-8"
+
+// Limits of the schema's simple types that the writer has to respect
+const (
+	maxText35       = 35
+	maxText34       = 34
+	maxText70       = 70
+	maxText140      = 140
+	maxFraction     = 5
+	maxTotalDigits  = 18
+	isoDate         = "2006-01-02"
+	isoDateTime     = "2006-01-02T15:04:05.999999999-07:00"
+	balanceOpening  = "OPBD"
+	balanceClosing  = "CLBD"
+	indicatorCredit = "CRDT"
+	indicatorDebit  = "DBIT"
+	statusBooked    = "BOOK"
+)
+
+// Header describes the message and the account and period its statements cover. The
+// period is the half-open range [Start, End), and times are written in Start's location.
+type Header struct {
+	MessageID   string
+	CreatedAt   time.Time
+	AccountID   string
+	AccountName string
+	Start       time.Time
+	End         time.Time
+}
+
+// Writer writes a camt.053 document. Each Statement starts the statement for a
+// currency, and the entries added after it must be in that currency.
+type Writer struct {
+	w        *bufio.Writer
+	enc      *xml.Encoder
+	header   Header
+	loc      *time.Location
+	currency string
+	written  int
+}
+
+// NewWriter writes the document's group header to w and returns a Writer to add
+// statements to
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	if h.MessageID == "" || len(h.MessageID) > maxText35 {
+		return nil, fmt.Errorf("message id %q must be 1 to %v characters", h.MessageID, maxText35)
+	}
+	if h.AccountID == "" || len(h.AccountID)+len("-XXX") > maxText35 {
+		return nil, fmt.Errorf("account id %q is too long for a camt.053 statement id", h.AccountID)
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &Writer{
+		w:      bw,
+		enc:    xml.NewEncoder(bw),
+		header: h,
+		loc:    h.Start.Location(),
+	}
+
+	_, err := bw.WriteString(xml.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cw.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: Namespace})
+	if err == nil {
+		err = cw.start("BkToCstmrStmt")
+	}
+	if err == nil {
+		err = cw.enc.EncodeElement(groupHeader{
+			MsgID:    h.MessageID,
+			CreDtTm:  h.CreatedAt.In(cw.loc).Format(isoDateTime),
+			MsgPgntn: pagination{PgNb: "1", LastPgInd: true},
+		}, element("GrpHdr"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// Statement ends the previous statement, if any, and starts the statement for a
+// currency with its booked balances at the start and end of the period
+func (cw *Writer) Statement(currency string, opening money.Amount, closing money.Amount) error {
+	// Check the balances fit before anything is written, so a currency that can't be
+	// expressed leaves the document as it was
+	for _, amount := range []money.Amount{opening, closing} {
+		_, err := amountFor(amount, currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cw.currency != "" {
+		err := cw.end("Stmt")
+		if err != nil {
+			return err
+		}
+	}
+
+	h := cw.header
+	err := cw.start("Stmt")
+	if err != nil {
+		return err
+	}
+	cw.currency = currency
+	cw.written++
+
+	// Statement ids only need to be unique per account, so they don't carry the period
+	for _, el := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"Id", fmt.Sprintf("%v-%v", h.AccountID, currency)},
+		{"CreDtTm", h.CreatedAt.In(cw.loc).Format(isoDateTime)},
+		{"FrToDt", period{
+			FrDtTm: h.Start.In(cw.loc).Format(isoDateTime),
+			ToDtTm: h.End.In(cw.loc).Add(-time.Second).Format(isoDateTime),
+		}},
+		{"Acct", account{
+			ID:  accountID{Othr: otherID{ID: truncate(h.AccountID, maxText34)}},
+			Ccy: currency,
+			Nm:  truncate(h.AccountName, maxText70),
+		}},
+	} {
+		err = cw.enc.EncodeElement(el.value, element(el.name))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range []struct {
+		code   string
+		amount money.Amount
+		date   time.Time
+	}{
+		{balanceOpening, opening, h.Start},
+		{balanceClosing, closing, h.End.Add(-time.Nanosecond)},
+	} {
+		amt, err := amountFor(b.amount, currency)
+		if err != nil {
+			return err
+		}
+
+		err = cw.enc.EncodeElement(cashBalance{
+			Tp:        balanceType{CdOrPrtry: balanceCode{Cd: b.code}},
+			Amt:       amt,
+			CdtDbtInd: indicatorCredit,
+			Dt:        dateChoice{Dt: b.date.In(cw.loc).Format(isoDate)},
+		}, element("Bal"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add writes one posting as a booked entry of the current statement
+func (cw *Writer) Add(e statement.Entry) error {
+	if cw.currency == "" {
+		return errors.New("entry added before any statement")
+	}
+	if e.Currency != cw.currency {
+		return fmt.Errorf("%v entry added to the %v statement", e.Currency, cw.currency)
+	}
+
+	amt, err := amountFor(e.Amount, e.Currency)
+	if err != nil {
+		return err
+	}
+
+	// Bank transaction codes from the ISO external code list: payments, received or
+	// issued credit transfers, of no more specific kind
+	indicator, fmly := indicatorCredit, "RCDT"
+	if e.Direction == model.TransactionDirectionDebit {
+		indicator, fmly = indicatorDebit, "ICDT"
+	}
+
+	posted := e.PostedAt.In(cw.loc)
+	ntry := reportEntry{
+		Amt:         amt,
+		CdtDbtInd:   indicator,
+		Sts:         statusBooked,
+		BookgDt:     dateTimeChoice{DtTm: posted.Format(isoDateTime)},
+		ValDt:       dateChoice{Dt: posted.Format(isoDate)},
+		AcctSvcrRef: truncate(e.TransactionID, maxText35),
+		BkTxCd: bankTransactionCode{Domn: domain{
+			Cd:   "PMNT",
+			Fmly: domainFamily{Cd: fmly, SubFmlyCd: "OTHR"},
+		}},
+	}
+	if memo := remittance(e.Memo); len(memo) > 0 {
+		ntry.NtryDtls = &entryDetails{TxDtls: transactionDetails{RmtInf: remittanceInformation{Ustrd: memo}}}
+	}
+
+	return cw.enc.EncodeElement(ntry, element("Ntry"))
+}
+
+// Close ends the document and flushes it
+func (cw *Writer) Close() error {
+	if cw.written == 0 {
+		return errors.New("a camt.053 document needs at least one statement")
+	}
+
+	err := cw.end("Stmt")
+	if err == nil {
+		err = cw.end("BkToCstmrStmt")
+	}
+	if err == nil {
+		err = cw.end("Document")
+	}
+	if err == nil {
+		err = cw.enc.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	return cw.w.Flush()
+}
+
+func (cw *Writer) start(name string, attrs ...xml.Attr) error {
+	return cw.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+}
+
+func (cw *Writer) end(name string) error {
+	return cw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+func element(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// CheckCurrency returns an error unless amounts in currency can be written in a
+// camt.053 document, which needs a three letter code and at most 5 decimals
+func CheckCurrency(currency string) error {
+	cur, ok := money.Lookup(currency)
+	if !ok || len(currency) != 3 {
+		return fmt.Errorf("currency %v has no ISO 4217 code for camt.053", currency)
+	}
+	if cur.Exponent > maxFraction {
+		return fmt.Errorf("currency %v has more decimals than camt.053 amounts allow", currency)
+	}
+	return nil
+}
+
+// amountFor renders an amount in major units, as long as it fits the schema's
+// ActiveOrHistoricCurrencyAndAmount: a three letter code, at most 5 decimals and
+// at most 18 digits
+func amountFor(amount money.Amount, currency string) (currencyAmount, error) {
+	err := CheckCurrency(currency)
+	if err != nil {
+		return currencyAmount{}, err
+	}
+	cur, _ := money.Lookup(currency)
+
+	digits := strings.TrimLeft(amount.String(), "0")
+	if len(digits) > maxTotalDigits {
+		return currencyAmount{}, fmt.Errorf("amount %v %v has more digits than camt.053 amounts allow", amount, currency)
+	}
+
+	return currencyAmount{Ccy: currency, Value: money.FormatAmount(amount, cur.Exponent)}, nil
+}
+
+// remittance splits a memo into the 140 character lines of unstructured remittance
+// information, without splitting a character
+func remittance(memo string) []string {
+	memo = strings.Join(strings.Fields(memo), " ")
+
+	var lines []string
+	for memo != "" {
+		line := truncate(memo, maxText140)
+		lines = append(lines, line)
+		memo = strings.TrimSpace(memo[len(line):])
+	}
+	return lines
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := 0
+	for i := range s {
+		if runes == n {
+			return s[:i]
+		}
+		runes++
+	}
+	return s
+}
@@ -0,0 +1,180 @@
+package camt
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/partyscript/bledger/internal/statement"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHeader(t *testing.T) Header {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	return Header{
+		MessageID:   "2Msg0000000000000000000000",
+		CreatedAt:   time.Date(2023, 12, 1, 9, 0, 0, 0, time.UTC),
+		AccountID:   "2Acct000000000000000000000",
+		AccountName: "Jane Doe",
+		Start:       time.Date(2023, 11, 1, 0, 0, 0, 0, loc),
+		End:         time.Date(2023, 12, 1, 0, 0, 0, 0, loc),
+	}
+}
+
+// schemaFile is the camt.053.001.02 schema as published by ISO 20022
+const schemaFile = "testdata/camt.053.001.02.xsd"
+
+// validateDocument checks doc against the published schema with xmllint, skipping the
+// test on machines without it
+func validateDocument(t *testing.T, doc string) error {
+	t.Helper()
+
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+
+	cmd := exec.Command(xmllint, "--noout", "--schema", schemaFile, "-")
+	cmd.Stdin = strings.NewReader(doc)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+
+	return nil
+}
+
+func TestDocument(t *testing.T) {
+	var out bytes.Buffer
+
+	w, err := NewWriter(&out, testHeader(t))
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.Statement("USD", money.NewAmount(10000), money.NewAmount(8766)))
+	assert.NoError(t, w.Add(statement.Entry{
+		PostedAt:      time.Date(2023, 11, 2, 15, 4, 5, 0, time.UTC),
+		TransactionID: "2Tx10000000000000000000000",
+		Direction:     model.TransactionDirectionDebit,
+		Amount:        money.NewAmount(1234),
+		Currency:      "USD",
+		Memo:          "Coffee & <cake>\n at \"Joe's\"",
+	}))
+	assert.NoError(t, w.Add(statement.Entry{
+		PostedAt:      time.Date(2023, 11, 3, 1, 0, 0, 0, time.UTC),
+		TransactionID: "2Tx20000000000000000000000",
+		Direction:     model.TransactionDirectionCredit,
+		Amount:        money.NewAmount(0),
+		Currency:      "USD",
+		Memo:          strings.Repeat("ab", 100),
+	}))
+
+	assert.NoError(t, w.Statement("JPY", money.NewAmount(500), money.NewAmount(500)))
+	assert.NoError(t, w.Statement("BHD", money.NewAmount(1), money.NewAmount(1)))
+	assert.NoError(t, w.Add(statement.Entry{
+		PostedAt:  time.Date(2023, 11, 30, 12, 0, 0, 0, time.UTC),
+		Direction: model.TransactionDirectionCredit,
+		Amount:    money.NewAmount(1),
+		Currency:  "BHD",
+	}))
+	assert.NoError(t, w.Close())
+
+	doc := out.String()
+	assert.True(t, strings.HasPrefix(doc, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt>`+
+		`<GrpHdr><MsgId>2Msg0000000000000000000000</MsgId><CreDtTm>2023-12-01T04:00:00-05:00</CreDtTm>`+
+		`<MsgPgntn><PgNb>1</PgNb><LastPgInd>true</LastPgInd></MsgPgntn></GrpHdr>`), doc)
+	assert.Equal(t, 3, strings.Count(doc, "<Stmt>"))
+
+	assert.Contains(t, doc, `<Stmt><Id>2Acct000000000000000000000-USD</Id><CreDtTm>2023-12-01T04:00:00-05:00</CreDtTm>`+
+		`<FrToDt><FrDtTm>2023-11-01T00:00:00-04:00</FrDtTm><ToDtTm>2023-11-30T23:59:59-05:00</ToDtTm></FrToDt>`+
+		`<Acct><Id><Othr><Id>2Acct000000000000000000000</Id></Othr></Id><Ccy>USD</Ccy><Nm>Jane Doe</Nm></Acct>`+
+		`<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="USD">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2023-11-01</Dt></Dt></Bal>`+
+		`<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="USD">87.66</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2023-11-30</Dt></Dt></Bal>`)
+
+	assert.Contains(t, doc, `<Ntry><Amt Ccy="USD">12.34</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>`+
+		`<BookgDt><DtTm>2023-11-02T11:04:05-04:00</DtTm></BookgDt><ValDt><Dt>2023-11-02</Dt></ValDt>`+
+		`<AcctSvcrRef>2Tx10000000000000000000000</AcctSvcrRef>`+
+		`<BkTxCd><Domn><Cd>PMNT</Cd><Fmly><Cd>ICDT</Cd><SubFmlyCd>OTHR</SubFmlyCd></Fmly></Domn></BkTxCd>`+
+		`<NtryDtls><TxDtls><RmtInf><Ustrd>Coffee &amp; &lt;cake&gt; at &#34;Joe&#39;s&#34;</Ustrd></RmtInf></TxDtls></NtryDtls></Ntry>`)
+
+	// Value dates are local, so a late evening posting in UTC lands on the day before
+	assert.Contains(t, doc, `<ValDt><Dt>2023-11-02</Dt></ValDt><AcctSvcrRef>2Tx20000000000000000000000</AcctSvcrRef>`)
+	assert.Contains(t, doc, `<RmtInf><Ustrd>`+strings.Repeat("ab", 70)+`</Ustrd><Ustrd>`+strings.Repeat("ab", 30)+`</Ustrd></RmtInf>`)
+	assert.Contains(t, doc, `<Amt Ccy="JPY">500</Amt>`)
+	assert.Contains(t, doc, `<Amt Ccy="BHD">0.001</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>`)
+	assert.Contains(t, doc, `<SubFmlyCd>OTHR</SubFmlyCd></Fmly></Domn></BkTxCd></Ntry></Stmt></BkToCstmrStmt></Document>`)
+
+	assert.NoError(t, validateDocument(t, doc))
+}
+
+func TestSchemaCatchesMistakes(t *testing.T) {
+	var out bytes.Buffer
+
+	w, err := NewWriter(&out, testHeader(t))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Statement("USD", money.NewAmount(1), money.NewAmount(1)))
+	assert.NoError(t, w.Close())
+
+	doc := out.String()
+	assert.NoError(t, validateDocument(t, doc))
+
+	for _, broken := range []string{
+		strings.Replace(doc, "<Cd>CLBD</Cd>", "<Cd>CLOSE</Cd>", 1),
+		strings.Replace(doc, `Ccy="USD">0.01<`, `Ccy="USD">0.000001<`, 1),
+		strings.Replace(doc, `Ccy="USD">0.01<`, `Ccy="usd">0.01<`, 1),
+		strings.Replace(doc, "<Ccy>USD</Ccy><Nm>Jane Doe</Nm>", "<Nm>Jane Doe</Nm><Ccy>USD</Ccy>", 1),
+		strings.Replace(doc, "<MsgId>2Msg0000000000000000000000</MsgId>", "<MsgId>"+strings.Repeat("x", 36)+"</MsgId>", 1),
+		strings.Replace(doc, "<CreDtTm>2023-12-01T04:00:00-05:00</CreDtTm><FrToDt>", "<FrToDt>", 1),
+		strings.Replace(doc, "camt.053.001.02", "camt.052.001.02", 1),
+	} {
+		assert.Error(t, validateDocument(t, broken))
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	h := testHeader(t)
+
+	w, err := NewWriter(&bytes.Buffer{}, h)
+	assert.NoError(t, err)
+	assert.EqualError(t, w.Add(statement.Entry{Currency: "USD"}), "entry added before any statement")
+	assert.EqualError(t, w.Close(), "a camt.053 document needs at least one statement")
+
+	assert.NoError(t, w.Statement("USD", money.NewAmount(0), money.NewAmount(0)))
+	assert.EqualError(t, w.Add(statement.Entry{Currency: "EUR"}), "EUR entry added to the USD statement")
+
+	// Digital assets have no ISO code or more decimals than camt.053 amounts allow
+	assert.NoError(t, CheckCurrency("BHD"))
+	assert.EqualError(t, CheckCurrency("ETH"), "currency ETH has more decimals than camt.053 amounts allow")
+	assert.EqualError(t, CheckCurrency("USDC"), "currency USDC has no ISO 4217 code for camt.053")
+	assert.Error(t, w.Statement("ETH", money.NewAmount(0), money.NewAmount(0)))
+	assert.Error(t, w.Statement("USDC", money.NewAmount(0), money.NewAmount(0)))
+
+	huge, err := money.ParseAmount(strings.Repeat("9", 19))
+	assert.NoError(t, err)
+	assert.Error(t, w.Statement("EUR", huge, huge))
+
+	h.MessageID = strings.Repeat("m", 36)
+	_, err = NewWriter(&bytes.Buffer{}, h)
+	assert.Error(t, err)
+
+	h = testHeader(t)
+	h.AccountID = strings.Repeat("a", 32)
+	_, err = NewWriter(&bytes.Buffer{}, h)
+	assert.Error(t, err)
+
+	// The statement that was open is still closed properly
+	var out bytes.Buffer
+	w, err = NewWriter(&out, testHeader(t))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Statement("USD", money.NewAmount(0), money.NewAmount(0)))
+	assert.Error(t, w.Statement("ETH", money.NewAmount(0), money.NewAmount(0)))
+	assert.NoError(t, w.Close())
+	assert.NoError(t, validateDocument(t, out.String()))
+}
@@ -0,0 +1,118 @@
+package camt
+
+// The elements below are the parts of the camt.053.001.02 schema the writer fills in.
+// Struct fields are declared in the order the schema's sequences require.
+
+// groupHeader is a GroupHeader42
+type groupHeader struct {
+	MsgID    string     `xml:"MsgId"`
+	CreDtTm  string     `xml:"CreDtTm"`
+	MsgPgntn pagination `xml:"MsgPgntn"`
+}
+
+// pagination is a Pagination
+type pagination struct {
+	PgNb      string `xml:"PgNb"`
+	LastPgInd bool   `xml:"LastPgInd"`
+}
+
+// period is a DateTimePeriodDetails
+type period struct {
+	FrDtTm string `xml:"FrDtTm"`
+	ToDtTm string `xml:"ToDtTm"`
+}
+
+// account is a CashAccount20
+type account struct {
+	ID  accountID `xml:"Id"`
+	Ccy string    `xml:"Ccy"`
+	Nm  string    `xml:"Nm,omitempty"`
+}
+
+// accountID is an AccountIdentification4Choice
+type accountID struct {
+	Othr otherID `xml:"Othr"`
+}
+
+// otherID is a GenericAccountIdentification1
+type otherID struct {
+	ID string `xml:"Id"`
+}
+
+// cashBalance is a CashBalance3
+type cashBalance struct {
+	Tp        balanceType    `xml:"Tp"`
+	Amt       currencyAmount `xml:"Amt"`
+	CdtDbtInd string         `xml:"CdtDbtInd"`
+	Dt        dateChoice     `xml:"Dt"`
+}
+
+// balanceType is a BalanceType12
+type balanceType struct {
+	CdOrPrtry balanceCode `xml:"CdOrPrtry"`
+}
+
+// balanceCode is a BalanceType5Choice
+type balanceCode struct {
+	Cd string `xml:"Cd"`
+}
+
+// currencyAmount is an ActiveOrHistoricCurrencyAndAmount
+type currencyAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+// dateChoice is a DateAndDateTimeChoice holding a date
+type dateChoice struct {
+	Dt string `xml:"Dt"`
+}
+
+// dateTimeChoice is a DateAndDateTimeChoice holding a date and time
+type dateTimeChoice struct {
+	DtTm string `xml:"DtTm"`
+}
+
+// reportEntry is a ReportEntry2
+type reportEntry struct {
+	Amt         currencyAmount      `xml:"Amt"`
+	CdtDbtInd   string              `xml:"CdtDbtInd"`
+	Sts         string              `xml:"Sts"`
+	BookgDt     dateTimeChoice      `xml:"BookgDt"`
+	ValDt       dateChoice          `xml:"ValDt"`
+	AcctSvcrRef string              `xml:"AcctSvcrRef,omitempty"`
+	BkTxCd      bankTransactionCode `xml:"BkTxCd"`
+	NtryDtls    *entryDetails       `xml:"NtryDtls,omitempty"`
+}
+
+// bankTransactionCode is a BankTransactionCodeStructure4
+type bankTransactionCode struct {
+	Domn domain `xml:"Domn"`
+}
+
+// domain is a BankTransactionCodeStructure5
+type domain struct {
+	Cd   string       `xml:"Cd"`
+	Fmly domainFamily `xml:"Fmly"`
+}
+
+// domainFamily is a BankTransactionCodeStructure6
+type domainFamily struct {
+	Cd        string `xml:"Cd"`
+	SubFmlyCd string `xml:"SubFmlyCd"`
+}
+
+// entryDetails is an EntryDetails1
+type entryDetails struct {
+	TxDtls transactionDetails `xml:"TxDtls"`
+}
+
+// transactionDetails is an EntryTransaction2
+type transactionDetails struct {
+	RmtInf remittanceInformation `xml:"RmtInf"`
+}
+
+// remittanceInformation is a RemittanceInformation5
+type remittanceInformation struct {
+	Ustrd []string `xml:"Ustrd"`
+}
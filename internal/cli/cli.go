@@ -0,0 +1,78 @@
+// Package cli runs the server binary's maintenance subcommands
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/partyscript/bledger/internal/controller"
+	"github.com/partyscript/bledger/internal/model"
+)
+
+const usage = `usage: server <command> [flags]
+
+commands:
+  verify [--repair]                          replay every account's history and report balance drift
+  import [--dry-run] [--format csv|beancount|ledger] [--columns spec] <file>
+                                             import transactions from a csv file, or accounts
+                                             and transactions from a plain text journal
+  export [--format beancount|ledger]         write accounts and transactions as a plain text journal
+`
+
+// verifier is the part of the verifier controller the verify command needs
+type verifier interface {
+	Verify(repair bool) (*model.VerificationReport, error)
+}
+
+// Run dispatches a subcommand and returns its exit code
+func Run(cm controller.Manager, args []string, out io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "verify":
+		return runVerify(&cm.Verifier, args[1:], out)
+	case "import":
+		return runImport(&cm.Transactions, cm.Cfg.Batch.MaxImportRows, args[1:], out)
+	case "export":
+		return runExport(&cm.Export, args[1:], out)
+	default:
+		fmt.Fprintf(out, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// runVerify prints the verification report as JSON. It exits 1 when drift was found
+// and left unrepaired, so a nightly job fails loudly.
+func runVerify(v verifier, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(out)
+	repair := fs.Bool("repair", false, "rewrite drifted balances to the replayed amounts")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	report, err := v.Verify(*repair)
+	if err != nil {
+		fmt.Fprintf(out, "verify failed: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(report)
+	if err != nil {
+		return 1
+	}
+
+	if report.AccountsDrifted > report.AccountsRepaired {
+		return 1
+	}
+	return 0
+}
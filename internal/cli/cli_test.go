@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/partyscript/bledger/internal/controller"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVerifier struct {
+	report *model.VerificationReport
+	err    error
+	repair bool
+}
+
+func (f *fakeVerifier) Verify(repair bool) (*model.VerificationReport, error) {
+	f.repair = repair
+	if f.report != nil {
+		f.report.Repair = repair
+		if repair {
+			f.report.AccountsRepaired = f.report.AccountsDrifted
+		}
+	}
+	return f.report, f.err
+}
+
+func TestRunVerify(t *testing.T) {
+	t.Run("clean ledger exits 0", func(t *testing.T) {
+		var out bytes.Buffer
+		v := &fakeVerifier{report: &model.VerificationReport{AccountsChecked: 3}}
+
+		assert.Equal(t, 0, runVerify(v, nil, &out))
+		assert.False(t, v.repair)
+		assert.Contains(t, out.String(), `"accounts_checked": 3`)
+	})
+
+	t.Run("unrepaired drift exits 1", func(t *testing.T) {
+		var out bytes.Buffer
+		v := &fakeVerifier{report: &model.VerificationReport{AccountsChecked: 3, AccountsDrifted: 1}}
+
+		assert.Equal(t, 1, runVerify(v, nil, &out))
+	})
+
+	t.Run("repaired drift exits 0", func(t *testing.T) {
+		var out bytes.Buffer
+		v := &fakeVerifier{report: &model.VerificationReport{AccountsChecked: 3, AccountsDrifted: 1}}
+
+		assert.Equal(t, 0, runVerify(v, []string{"--repair"}, &out))
+		assert.True(t, v.repair)
+	})
+
+	t.Run("verifier error exits 1", func(t *testing.T) {
+		var out bytes.Buffer
+		v := &fakeVerifier{err: errors.New("db down")}
+
+		assert.Equal(t, 1, runVerify(v, nil, &out))
+		assert.Contains(t, out.String(), "db down")
+	})
+
+	t.Run("bad flag exits 2", func(t *testing.T) {
+		var out bytes.Buffer
+
+		assert.Equal(t, 2, runVerify(&fakeVerifier{}, []string{"--nope"}, &out))
+	})
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.Equal(t, 2, Run(controller.Manager{}, []string{"nope"}, &out))
+	assert.Contains(t, out.String(), "unknown command")
+	assert.Equal(t, 2, Run(controller.Manager{}, nil, &out))
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/plaintext"
+)
+
+// ledgerStreamer is the part of the export controller the export command needs
+type ledgerStreamer interface {
+	StreamLedger(accountFn func(model.Account) error, txFn func(model.Transaction) error) error
+}
+
+// runExport writes every account and closed transaction to out as a plain text journal
+func runExport(ls ledgerStreamer, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(out)
+	format := fs.String("format", string(plaintext.FormatBeancount), "journal syntax, beancount or ledger")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	w, err := plaintext.NewWriter(plaintext.Format(*format), out)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+
+	err = ls.StreamLedger(w.Account, w.Transaction)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(out, "export failed: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+// fakeStreamer hands over a single account with a single completed credit
+type fakeStreamer struct {
+	err error
+}
+
+func (f *fakeStreamer) StreamLedger(accountFn func(model.Account) error, txFn func(model.Transaction) error) error {
+	at := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	acct := model.Account{ID: "acct_1", Balance: datatypes.JSON(`[{"amount":"100","currency":"USD"}]`)}
+	acct.CreatedAt = at
+	err := accountFn(acct)
+	if err != nil {
+		return err
+	}
+
+	if f.err != nil {
+		return f.err
+	}
+
+	tx := model.Transaction{
+		ID:          "tx_1",
+		AccountID:   "acct_1",
+		Money:       datatypes.JSON(`{"amount":"100","currency":"USD"}`),
+		Direction:   model.TransactionDirectionCredit,
+		State:       model.TransactionStateCompleted,
+		CompletedAt: &at,
+	}
+	return txFn(tx)
+}
+
+func TestRunExport(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 0, runExport(&fakeStreamer{}, nil, &out))
+	assert.Contains(t, out.String(), "2023-05-01 open Assets:Bledger:Acct-acct_1 USD\n")
+	assert.Regexp(t, `Assets:Bledger:Acct-acct_1 +1\.00 USD`, out.String())
+
+	out.Reset()
+	assert.Equal(t, 0, runExport(&fakeStreamer{}, []string{"--format", "ledger"}, &out))
+	assert.Contains(t, out.String(), "account Assets:Bledger:Acct-acct_1\n")
+	assert.Contains(t, out.String(), "2023/05/01 *\n")
+
+	out.Reset()
+	assert.Equal(t, 2, runExport(&fakeStreamer{}, []string{"--format", "gnucash"}, &out))
+	assert.Contains(t, out.String(), "unknown export format gnucash")
+
+	out.Reset()
+	assert.Equal(t, 1, runExport(&fakeStreamer{err: errors.New("connection reset")}, nil, &out))
+	assert.Contains(t, out.String(), "export failed: connection reset")
+}
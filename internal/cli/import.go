@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/importer"
+	"github.com/partyscript/bledger/internal/plaintext"
+)
+
+// formatCSV is the import format for a csv file of transactions
+const formatCSV = "csv"
+
+// transactionImporter is the part of the transactions controller the import command needs
+type transactionImporter interface {
+	ImportTransactions(rows []importer.Row, dryRun bool, actor string) (*importer.Report, error)
+	ImportJournal(j *plaintext.Journal, dryRun bool, actor string) (*importer.Report, error)
+}
+
+// runImport imports a csv file of transactions, or a Beancount or ledger-cli journal
+// along with its accounts, and prints the line by line report. It exits 1 when any
+// line failed, in which case nothing was committed.
+func runImport(ti transactionImporter, maxRows int, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "validate every row and report, without committing")
+	format := fs.String("format", formatCSV, "file format, csv, beancount or ledger")
+	columns := fs.String("columns", "", "map fields to csv headers, e.g. amount=Amount,account_id=Account")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(out, "import takes exactly one file")
+		return 2
+	}
+
+	var cols importer.Columns
+	switch *format {
+	case formatCSV:
+		cols, err = importer.ParseColumns(*columns)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			return 2
+		}
+	case string(plaintext.FormatBeancount), string(plaintext.FormatLedger):
+		if *columns != "" {
+			fmt.Fprintln(out, "--columns only applies to csv files")
+			return 2
+		}
+	default:
+		fmt.Fprintf(out, "unknown import format %v\n", *format)
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	defer f.Close()
+
+	var report *importer.Report
+	if *format == formatCSV {
+		var rows []importer.Row
+		rows, err = importer.Parse(f, cols, maxRows)
+		if err == nil {
+			report, err = ti.ImportTransactions(rows, *dryRun, common.CLIActor)
+		}
+	} else {
+		var j *plaintext.Journal
+		j, err = plaintext.Parse(plaintext.Format(*format), f, maxRows)
+		if err == nil {
+			report, err = ti.ImportJournal(j, *dryRun, common.CLIActor)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(out, "import failed: %v\n", err)
+		return 1
+	}
+
+	err = report.WriteText(out)
+	if err != nil || report.Invalid > 0 {
+		return 1
+	}
+	return 0
+}
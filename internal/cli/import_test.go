@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/partyscript/bledger/internal/importer"
+	"github.com/partyscript/bledger/internal/plaintext"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeImporter fails rows whose account is "missing" and commits the rest
+type fakeImporter struct {
+	rows    []importer.Row
+	journal *plaintext.Journal
+	dryRun  bool
+	err     error
+}
+
+func (f *fakeImporter) ImportTransactions(rows []importer.Row, dryRun bool, actor string) (*importer.Report, error) {
+	f.rows, f.dryRun = rows, dryRun
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	report := &importer.Report{DryRun: dryRun, Rows: len(rows)}
+	for _, row := range rows {
+		switch {
+		case row.Err != nil:
+			report.Fail(row.Line, row.Err)
+		case row.Request.AccountID == "missing":
+			report.Fail(row.Line, errors.New("account missing not found"))
+		default:
+			report.Valid++
+		}
+	}
+	report.Committed = !dryRun && report.Invalid == 0
+
+	return report, nil
+}
+
+// ImportJournal commits a journal unless it has errors
+func (f *fakeImporter) ImportJournal(j *plaintext.Journal, dryRun bool, actor string) (*importer.Report, error) {
+	f.journal, f.dryRun = j, dryRun
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	report := &importer.Report{DryRun: dryRun, Accounts: len(j.Accounts), Rows: len(j.Postings), Valid: len(j.Postings)}
+	for _, e := range j.Errors {
+		report.Fail(e.Line, errors.New(e.Error))
+	}
+	report.Committed = !dryRun && report.Invalid == 0
+
+	return report, nil
+}
+
+func writeCSV(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "import.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestRunImport(t *testing.T) {
+	good := writeCSV(t, "account_id,direction,amount,currency\nacct_1,CREDIT,100,USD\n")
+	bad := writeCSV(t, "account_id,direction,amount,currency\nacct_1,CREDIT,100,USD\nmissing,CREDIT,1,USD\n")
+
+	t.Run("valid file commits", func(t *testing.T) {
+		var out bytes.Buffer
+		fi := &fakeImporter{}
+
+		assert.Equal(t, 0, runImport(fi, 10, []string{good}, &out))
+		assert.False(t, fi.dryRun)
+		assert.Len(t, fi.rows, 1)
+		assert.Contains(t, out.String(), "1 rows, 1 valid, 0 invalid: committed")
+	})
+
+	t.Run("dry run reports failing lines", func(t *testing.T) {
+		var out bytes.Buffer
+		fi := &fakeImporter{}
+
+		assert.Equal(t, 1, runImport(fi, 10, []string{"--dry-run", bad}, &out))
+		assert.True(t, fi.dryRun)
+		assert.Contains(t, out.String(), "line 3: account missing not found\n")
+		assert.Contains(t, out.String(), "dry run, nothing committed")
+	})
+
+	t.Run("custom columns", func(t *testing.T) {
+		var out bytes.Buffer
+		fi := &fakeImporter{}
+		renamed := writeCSV(t, "Account,direction,amount,currency\nacct_1,DEBIT,5,EUR\n")
+
+		assert.Equal(t, 0, runImport(fi, 10, []string{"--columns", "account_id=Account", renamed}, &out))
+		assert.Equal(t, "acct_1", fi.rows[0].Request.AccountID)
+	})
+
+	t.Run("too many rows", func(t *testing.T) {
+		var out bytes.Buffer
+
+		assert.Equal(t, 1, runImport(&fakeImporter{}, 1, []string{bad}, &out))
+		assert.Contains(t, out.String(), "more than 1 rows")
+	})
+
+	t.Run("importer error", func(t *testing.T) {
+		var out bytes.Buffer
+
+		assert.Equal(t, 1, runImport(&fakeImporter{err: errors.New("db down")}, 10, []string{good}, &out))
+		assert.Contains(t, out.String(), "db down")
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		var out bytes.Buffer
+
+		assert.Equal(t, 2, runImport(&fakeImporter{}, 10, nil, &out))
+		assert.Equal(t, 2, runImport(&fakeImporter{}, 10, []string{"--columns", "colour=x", good}, &out))
+		assert.Equal(t, 2, runImport(&fakeImporter{}, 10, []string{"--format", "qif", good}, &out))
+		assert.Equal(t, 2, runImport(&fakeImporter{}, 10, []string{"--format", "ledger", "--columns", "amount=Amount", good}, &out))
+		assert.Equal(t, 1, runImport(&fakeImporter{}, 10, []string{filepath.Join(t.TempDir(), "nope.csv")}, &out))
+	})
+}
+
+func TestRunImportJournal(t *testing.T) {
+	journal := writeCSV(t, `2023-01-01 open Assets:Checking USD
+
+2023-01-02 * "Pay"
+  Assets:Checking  10.00 USD
+  Income:Salary
+
+2023-01-03 balance Assets:Checking 10.00 USD
+`)
+
+	var out bytes.Buffer
+	fi := &fakeImporter{}
+
+	assert.Equal(t, 1, runImport(fi, 10, []string{"--format", "beancount", "--dry-run", journal}, &out))
+	assert.True(t, fi.dryRun)
+	assert.Len(t, fi.journal.Accounts, 1)
+	assert.Len(t, fi.journal.Postings, 1)
+	assert.Equal(t, "line 7: \"balance\" directives are not supported\n"+
+		"1 accounts, 1 rows, 1 valid, 1 invalid: dry run, nothing committed\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 1, runImport(fi, 0, []string{"--format", "beancount", journal}, &out))
+	assert.Contains(t, out.String(), "import failed: journal has more than 0 postings")
+}
@@ -0,0 +1,197 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// CompletedAt returns when a transaction's balance change was posted. Rows written
+// before completed_at existed fall back to their last update (or, once reversed,
+// their creation) time.
+func CompletedAt(tx model.Transaction) time.Time {
+	if tx.CompletedAt != nil {
+		return *tx.CompletedAt
+	}
+	if tx.State == model.TransactionStateReversed {
+		return tx.CreatedAt
+	}
+	return tx.UpdatedAt
+}
+
+// ReversedAt returns when a legacy REVERSED transaction's balance change was undone.
+// Rows reversed before reversed_at was recorded fall back to their last update.
+func ReversedAt(tx model.Transaction) time.Time {
+	if tx.ReversedAt != nil {
+		return *tx.ReversedAt
+	}
+	return tx.UpdatedAt
+}
+
+// Posting is one movement a transaction made on its account's posted balance
+type Posting struct {
+	At        time.Time
+	Direction model.TransactionDirection
+	// Reversal marks the movement that undid a legacy in-place reversal
+	Reversal bool
+}
+
+// Postings returns the movements a transaction made on the posted balance, oldest
+// first: one for a completed transaction, plus an opposite one for a transaction
+// reversed in place, and none for anything that never completed
+func Postings(tx model.Transaction) []Posting {
+	switch tx.State {
+	case model.TransactionStateCompleted:
+		return []Posting{{At: CompletedAt(tx), Direction: tx.Direction}}
+	case model.TransactionStateReversed:
+		opposite := model.TransactionDirectionCredit
+		if tx.Direction == model.TransactionDirectionCredit {
+			opposite = model.TransactionDirectionDebit
+		}
+		return []Posting{
+			{At: CompletedAt(tx), Direction: tx.Direction},
+			{At: ReversedAt(tx), Direction: opposite, Reversal: true},
+		}
+	default:
+		return nil
+	}
+}
+
+// BalanceAsOf replays an account's transactions and returns the posted balance and
+// the pending credits and debits in each currency as they stood at asOf. When base is
+// set, the posted balance starts from that snapshot and only postings made after it
+// are replayed, so txs only needs the tail of the history plus anything open at asOf.
+func BalanceAsOf(currencies []string, base *model.BalanceSnapshot, txs []model.Transaction, asOf time.Time) (*model.AccountBalanceResponse, error) {
+	var since time.Time
+	start := model.AccountBalances{}
+	if base != nil {
+		since = base.TakenAt
+		err := json.Unmarshal(base.Balance, &start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	credits := map[string]money.Amount{}
+	debits := map[string]money.Amount{}
+	pendingCredits := map[string]money.Amount{}
+	pendingDebits := map[string]money.Amount{}
+
+	// postedIn reports whether a posting made at t falls after the snapshot and by asOf
+	postedIn := func(t time.Time) bool {
+		return t.After(since) && !t.After(asOf)
+	}
+
+	for _, tx := range txs {
+		if tx.CreatedAt.After(asOf) {
+			continue
+		}
+
+		txMoney := new(model.TransactionMoney)
+		err := json.Unmarshal(tx.Money, txMoney)
+		if err != nil {
+			return nil, err
+		}
+
+		credit := tx.Direction == model.TransactionDirectionCredit
+		var add, undo map[string]money.Amount
+
+		switch tx.State {
+		case model.TransactionStatePending:
+		case model.TransactionStateCompleted:
+			if !CompletedAt(tx).After(asOf) {
+				if !postedIn(CompletedAt(tx)) {
+					continue
+				}
+				add = debits
+				if credit {
+					add = credits
+				}
+			}
+		case model.TransactionStateReversed:
+			// A reversal undoes the posting, so it only counts between the two
+			if !ReversedAt(tx).After(asOf) {
+				if postedIn(CompletedAt(tx)) == postedIn(ReversedAt(tx)) {
+					continue
+				}
+				// The snapshot already holds the posting, so only its undoing is left
+				undo = credits
+				if credit {
+					undo = debits
+				}
+			} else if !CompletedAt(tx).After(asOf) {
+				if !postedIn(CompletedAt(tx)) {
+					continue
+				}
+				add = debits
+				if credit {
+					add = credits
+				}
+			}
+		case model.TransactionStateExpired:
+			// An expired transaction was only ever pending, up until its expiry
+			if tx.ExpiresAt == nil || !tx.ExpiresAt.After(asOf) {
+				continue
+			}
+		case model.TransactionStateVoided:
+			if tx.VoidedAt == nil || !tx.VoidedAt.After(asOf) {
+				continue
+			}
+		case model.TransactionStateFailed:
+			// Transactions failed on create were never pending
+			if tx.FailedAt == nil || !tx.FailedAt.After(asOf) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		totals := pendingDebits
+		switch {
+		case add != nil:
+			totals = add
+		case undo != nil:
+			totals = undo
+		case credit:
+			totals = pendingCredits
+		}
+
+		totals[txMoney.Currency], err = totals[txMoney.Currency].Add(txMoney.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := &model.AccountBalanceResponse{
+		AsOf:           asOf,
+		Balance:        model.AccountBalances{},
+		PendingCredits: model.AccountBalances{},
+		PendingDebits:  model.AccountBalances{},
+	}
+
+	for _, c := range currencies {
+		var opening money.Amount
+		if m := start.Find(c); m != nil {
+			opening = m.Amount
+		}
+
+		in, err := opening.Add(credits[c])
+		if err != nil {
+			return nil, err
+		}
+
+		bal, err := in.Sub(debits[c])
+		if err != nil {
+			return nil, fmt.Errorf("transaction history debits more than it credits in %v", c)
+		}
+
+		res.Balance = append(res.Balance, model.AccountMoney{Amount: bal, Currency: c})
+		res.PendingCredits = append(res.PendingCredits, model.AccountMoney{Amount: pendingCredits[c], Currency: c})
+		res.PendingDebits = append(res.PendingDebits, model.AccountMoney{Amount: pendingDebits[c], Currency: c})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,174 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func testTx(dir model.TransactionDirection, amt uint64, state model.TransactionState, created time.Time, completed *time.Time, reversed *time.Time) model.Transaction {
+	b, _ := json.Marshal(model.TransactionMoney{Amount: money.NewAmount(amt), Currency: "USD"})
+	tx := model.Transaction{
+		Money:       datatypes.JSON(b),
+		Direction:   dir,
+		State:       state,
+		CompletedAt: completed,
+		ReversedAt:  reversed,
+	}
+	tx.CreatedAt = created
+	tx.UpdatedAt = created
+	return tx
+}
+
+func expiredTx(dir model.TransactionDirection, amt uint64, created time.Time, expires *time.Time) model.Transaction {
+	tx := testTx(dir, amt, model.TransactionStateExpired, created, nil, nil)
+	tx.ExpiresAt = expires
+	return tx
+}
+
+func closedTx(state model.TransactionState, amt uint64, created time.Time, closed *time.Time) model.Transaction {
+	tx := testTx(model.TransactionDirectionCredit, amt, state, created, nil, nil)
+	if state == model.TransactionStateVoided {
+		tx.VoidedAt = closed
+	} else {
+		tx.FailedAt = closed
+	}
+	return tx
+}
+
+func at(hour int) *time.Time {
+	t := time.Date(2023, 5, 1, hour, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestBalanceAsOf(t *testing.T) {
+	txs := []model.Transaction{
+		testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *at(1), at(1), nil),
+		testTx(model.TransactionDirectionDebit, 30, model.TransactionStateCompleted, *at(2), at(4), nil),
+		testTx(model.TransactionDirectionCredit, 50, model.TransactionStateReversed, *at(3), at(3), at(5)),
+		testTx(model.TransactionDirectionCredit, 7, model.TransactionStatePending, *at(3), nil, nil),
+		testTx(model.TransactionDirectionDebit, 1000, model.TransactionStateFailed, *at(3), nil, nil),
+		expiredTx(model.TransactionDirectionDebit, 5, *at(2), at(4)),
+		closedTx(model.TransactionStateVoided, 3, *at(3), at(5)),
+		closedTx(model.TransactionStateFailed, 2, *at(4), at(5)),
+	}
+
+	testCases := []struct {
+		asOf           time.Time
+		balance        string
+		pendingCredits string
+		pendingDebits  string
+	}{
+		{asOf: *at(0), balance: "0", pendingCredits: "0", pendingDebits: "0"},
+		{asOf: *at(1), balance: "100", pendingCredits: "0", pendingDebits: "0"},
+		{asOf: *at(2), balance: "100", pendingCredits: "0", pendingDebits: "35"},
+		{asOf: *at(3), balance: "150", pendingCredits: "10", pendingDebits: "35"},
+		{asOf: *at(4), balance: "120", pendingCredits: "12", pendingDebits: "0"},
+		{asOf: *at(5), balance: "70", pendingCredits: "7", pendingDebits: "0"},
+	}
+
+	for _, tc := range testCases {
+		bal, err := BalanceAsOf([]string{"USD"}, nil, txs, tc.asOf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.balance, bal.Balance.Find("USD").Amount.String(), tc.asOf)
+		assert.Equal(t, tc.pendingCredits, bal.PendingCredits.Find("USD").Amount.String(), tc.asOf)
+		assert.Equal(t, tc.pendingDebits, bal.PendingDebits.Find("USD").Amount.String(), tc.asOf)
+	}
+}
+
+func TestBalanceAsOfFromSnapshot(t *testing.T) {
+	txs := []model.Transaction{
+		testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *at(1), at(1), nil),
+		testTx(model.TransactionDirectionDebit, 30, model.TransactionStateCompleted, *at(2), at(4), nil),
+		testTx(model.TransactionDirectionCredit, 50, model.TransactionStateReversed, *at(3), at(3), at(5)),
+		testTx(model.TransactionDirectionCredit, 7, model.TransactionStatePending, *at(3), nil, nil),
+		expiredTx(model.TransactionDirectionDebit, 5, *at(2), at(4)),
+		closedTx(model.TransactionStateVoided, 3, *at(3), at(5)),
+	}
+
+	// Starting from a snapshot taken at any earlier hour must give the same answer
+	// as replaying the whole history
+	for taken := 0; taken <= 6; taken++ {
+		snap, err := BalanceAsOf([]string{"USD"}, nil, txs, *at(taken))
+		assert.NoError(t, err)
+
+		b, _ := json.Marshal(snap.Balance)
+		base := &model.BalanceSnapshot{TakenAt: *at(taken), Balance: datatypes.JSON(b)}
+
+		for asOf := taken; asOf <= 6; asOf++ {
+			full, err := BalanceAsOf([]string{"USD"}, nil, txs, *at(asOf))
+			assert.NoError(t, err)
+
+			tail, err := BalanceAsOf([]string{"USD"}, base, txs, *at(asOf))
+			assert.NoError(t, err)
+
+			assert.Equal(t, full.Balance, tail.Balance, "snapshot %v, as of %v", taken, asOf)
+			assert.Equal(t, full.PendingCredits, tail.PendingCredits, "snapshot %v, as of %v", taken, asOf)
+			assert.Equal(t, full.PendingDebits, tail.PendingDebits, "snapshot %v, as of %v", taken, asOf)
+		}
+	}
+}
+
+func TestBalanceAsOfSnapshotOnly(t *testing.T) {
+	base := &model.BalanceSnapshot{
+		TakenAt: *at(2),
+		Balance: datatypes.JSON(`[{"amount":"500","currency":"USD"}]`),
+	}
+
+	// Postings at or before the snapshot are already in it and aren't counted twice
+	txs := []model.Transaction{
+		testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *at(1), at(2), nil),
+		testTx(model.TransactionDirectionDebit, 20, model.TransactionStateCompleted, *at(3), at(3), nil),
+	}
+
+	bal, err := BalanceAsOf([]string{"USD", "EUR"}, base, txs, *at(4))
+	assert.NoError(t, err)
+	assert.Equal(t, "480", bal.Balance.Find("USD").Amount.String())
+	assert.Equal(t, "0", bal.Balance.Find("EUR").Amount.String())
+}
+
+func TestBalanceAsOfPerCurrency(t *testing.T) {
+	usd := testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *at(1), at(1), nil)
+	eur := testTx(model.TransactionDirectionCredit, 40, model.TransactionStateCompleted, *at(1), at(1), nil)
+	eur.Money = datatypes.JSON(`{"amount":40,"currency":"EUR"}`)
+
+	bal, err := BalanceAsOf([]string{"EUR", "USD", "GBP"}, nil, []model.Transaction{usd, eur}, *at(2))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EUR", "USD", "GBP"}, bal.Balance.Currencies())
+	assert.Equal(t, "40", bal.Balance.Find("EUR").Amount.String())
+	assert.Equal(t, "100", bal.Balance.Find("USD").Amount.String())
+	assert.Equal(t, "0", bal.Balance.Find("GBP").Amount.String())
+}
+
+func TestAccountBalancesUnmarshalLegacy(t *testing.T) {
+	var bal model.AccountBalances
+	err := json.Unmarshal([]byte(`{"amount":12,"currency":"USD"}`), &bal)
+	assert.NoError(t, err)
+	assert.Len(t, bal, 1)
+	assert.Equal(t, "12", bal[0].Amount.String())
+	assert.Equal(t, "USD", bal[0].Currency)
+
+	err = json.Unmarshal([]byte(`[{"amount":1,"currency":"USD"},{"amount":2,"currency":"EUR"}]`), &bal)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", bal.Find("EUR").Amount.String())
+	assert.Nil(t, bal.Find("GBP"))
+}
+
+func TestPostings(t *testing.T) {
+	completed := testTx(model.TransactionDirectionCredit, 10, model.TransactionStateCompleted, *at(1), at(2), nil)
+	assert.Equal(t, []Posting{{At: *at(2), Direction: model.TransactionDirectionCredit}}, Postings(completed))
+
+	reversed := testTx(model.TransactionDirectionDebit, 10, model.TransactionStateReversed, *at(1), at(2), at(3))
+	assert.Equal(t, []Posting{
+		{At: *at(2), Direction: model.TransactionDirectionDebit},
+		{At: *at(3), Direction: model.TransactionDirectionCredit, Reversal: true},
+	}, Postings(reversed))
+
+	pending := testTx(model.TransactionDirectionCredit, 10, model.TransactionStatePending, *at(1), nil, nil)
+	assert.Empty(t, Postings(pending))
+}
@@ -2,3 +2,16 @@ package common
 
 // IdempotencyHeader is the header used to store the idempotency key
 const IdempotencyHeader = "Idempotency-Key"
+
+// ActorHeader is the header naming who or what is making a request, recorded on
+// the transaction history
+const ActorHeader = "X-Actor"
+
+// DefaultActor is the actor recorded when a request doesn't name one
+const DefaultActor = "api"
+
+// SweeperActor is the actor recorded for changes made by the expiry sweeper
+const SweeperActor = "system:sweeper"
+
+// CLIActor is the actor recorded for changes made by the server's subcommands
+const CLIActor = "system:cli"
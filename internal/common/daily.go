@@ -0,0 +1,176 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	// Embed the time zone database so tz lookups work on images without one
+	_ "time/tzdata"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// MaxDailyRange is the most days a daily balance series can cover
+const MaxDailyRange = 366
+
+// dateLayout is the layout of a local calendar date
+const dateLayout = "2006-01-02"
+
+// DayWindow is one local calendar day as the half-open instant range [Start, End).
+// Days that cross a daylight saving change are 23 or 25 hours long.
+type DayWindow struct {
+	Date  string
+	Start time.Time
+	End   time.Time
+}
+
+// DateRange returns the instants that open from and close to, two inclusive local dates
+// in the IANA time zone tz, which defaults to UTC, as the half-open range [start, end)
+func DateRange(from string, to string, tz string) (time.Time, time.Time, error) {
+	if tz == "" {
+		tz = "UTC"
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown time zone %v", tz)
+	}
+
+	first, err := time.ParseInLocation(dateLayout, from, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("from must be a date like 2006-01-02")
+	}
+
+	last, err := time.ParseInLocation(dateLayout, to, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("to must be a date like 2006-01-02")
+	}
+
+	if last.Before(first) {
+		return time.Time{}, time.Time{}, errors.New("to must not be before from")
+	}
+
+	y, m, d := last.Date()
+	return first, time.Date(y, m, d+1, 0, 0, 0, 0, loc), nil
+}
+
+// DailyWindows returns one window per local day from from to to, inclusive, in the
+// IANA time zone tz, which defaults to UTC
+func DailyWindows(from string, to string, tz string) ([]DayWindow, error) {
+	first, end, err := DateRange(from, to, tz)
+	if err != nil {
+		return nil, err
+	}
+	loc := first.Location()
+
+	var windows []DayWindow
+	for y, m, d := first.Date(); ; d++ {
+		// time.Date normalizes the day overflow into the next month or year and finds
+		// local midnight however long the previous day was
+		start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		if !start.Before(end) {
+			return windows, nil
+		}
+		if len(windows) == MaxDailyRange {
+			return nil, fmt.Errorf("date range can't cover more than %v days", MaxDailyRange)
+		}
+
+		windows = append(windows, DayWindow{
+			Date:  start.Format(dateLayout),
+			Start: start,
+			End:   time.Date(y, m, d+1, 0, 0, 0, 0, loc),
+		})
+	}
+}
+
+// DailyBalances buckets an account's postings into each window and returns one row per
+// day per currency. opening is the posted balance just before the first window opens,
+// and each day opens with the previous day's closing balance.
+func DailyBalances(currencies []string, opening model.AccountBalances, txs []model.Transaction, windows []DayWindow) ([]model.DailyBalance, error) {
+	type bucket struct {
+		credits map[string]money.Amount
+		debits  map[string]money.Amount
+	}
+
+	buckets := make([]bucket, len(windows))
+	for i := range buckets {
+		buckets[i] = bucket{credits: map[string]money.Amount{}, debits: map[string]money.Amount{}}
+	}
+
+	// dayOf returns the index of the window holding t, or -1
+	dayOf := func(t time.Time) int {
+		i := sort.Search(len(windows), func(i int) bool { return windows[i].End.After(t) })
+		if i == len(windows) || t.Before(windows[i].Start) {
+			return -1
+		}
+		return i
+	}
+
+	for _, tx := range txs {
+		postings := Postings(tx)
+		if len(postings) == 0 {
+			continue
+		}
+
+		txMoney := new(model.TransactionMoney)
+		err := json.Unmarshal(tx.Money, txMoney)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range postings {
+			i := dayOf(p.At)
+			if i < 0 {
+				continue
+			}
+
+			totals := buckets[i].debits
+			if p.Direction == model.TransactionDirectionCredit {
+				totals = buckets[i].credits
+			}
+
+			totals[txMoney.Currency], err = totals[txMoney.Currency].Add(txMoney.Amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	balances := map[string]money.Amount{}
+	for _, c := range currencies {
+		if m := opening.Find(c); m != nil {
+			balances[c] = m.Amount
+		}
+	}
+
+	days := make([]model.DailyBalance, 0, len(windows)*len(currencies))
+	for i, w := range windows {
+		for _, c := range currencies {
+			in, err := balances[c].Add(buckets[i].credits[c])
+			if err != nil {
+				return nil, err
+			}
+
+			closing, err := in.Sub(buckets[i].debits[c])
+			if err != nil {
+				return nil, fmt.Errorf("transaction history debits more than it credits in %v on %v", c, w.Date)
+			}
+
+			days = append(days, model.DailyBalance{
+				Date:     w.Date,
+				Currency: c,
+				Opening:  balances[c],
+				Credits:  buckets[i].credits[c],
+				Debits:   buckets[i].debits[c],
+				Closing:  closing,
+			})
+			balances[c] = closing
+		}
+	}
+
+	return days, nil
+}
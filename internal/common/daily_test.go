@@ -0,0 +1,121 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyWindows(t *testing.T) {
+	windows, err := DailyWindows("2023-01-30", "2023-02-02", "")
+	assert.NoError(t, err)
+	assert.Len(t, windows, 4)
+	assert.Equal(t, "2023-01-31", windows[1].Date)
+	assert.Equal(t, "2023-02-01", windows[2].Date)
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), windows[1].End)
+
+	testCases := []struct {
+		name     string
+		from, to string
+		tz       string
+		err      bool
+	}{
+		{name: "single day", from: "2023-05-01", to: "2023-05-01", tz: "UTC"},
+		{name: "to before from", from: "2023-05-02", to: "2023-05-01", err: true},
+		{name: "unknown zone", from: "2023-05-01", to: "2023-05-02", tz: "Mars/Olympus", err: true},
+		{name: "bad date", from: "05/01/2023", to: "2023-05-02", err: true},
+		{name: "a leap year", from: "2024-01-01", to: "2024-12-31"},
+		{name: "too long", from: "2023-01-01", to: "2024-01-02", err: true},
+	}
+
+	for _, tc := range testCases {
+		_, err := DailyWindows(tc.from, tc.to, tc.tz)
+		if tc.err {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
+
+func TestDailyWindowsDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// Clocks sprang forward on 2023-03-12 and fell back on 2023-11-05
+	spring, err := DailyWindows("2023-03-11", "2023-03-13", "America/New_York")
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, spring[0].End.Sub(spring[0].Start))
+	assert.Equal(t, 23*time.Hour, spring[1].End.Sub(spring[1].Start))
+	assert.Equal(t, 24*time.Hour, spring[2].End.Sub(spring[2].Start))
+
+	fall, err := DailyWindows("2023-11-05", "2023-11-05", "America/New_York")
+	assert.NoError(t, err)
+	assert.Equal(t, 25*time.Hour, fall[0].End.Sub(fall[0].Start))
+	assert.Equal(t, time.Date(2023, 11, 5, 4, 0, 0, 0, time.UTC), fall[0].Start.UTC())
+	assert.Equal(t, time.Date(2023, 11, 6, 5, 0, 0, 0, time.UTC), fall[0].End.UTC())
+
+	// Windows are contiguous, so no instant is dropped or counted twice
+	for i := 1; i < len(spring); i++ {
+		assert.Equal(t, spring[i-1].End, spring[i].Start)
+		assert.Equal(t, ny, spring[i].Start.Location())
+	}
+}
+
+func TestDailyBalances(t *testing.T) {
+	utc := func(day, hour int) *time.Time {
+		t := time.Date(2023, 11, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	txs := []model.Transaction{
+		// 2023-11-04 23:00 New York time
+		testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *utc(5, 3), utc(5, 3), nil),
+		// 2023-11-05 23:30 New York time, after the extra hour, still the 5th
+		testTx(model.TransactionDirectionDebit, 30, model.TransactionStateCompleted, *utc(6, 4), utc(6, 4), nil),
+		// 2023-11-06 00:00 New York time, the first instant of the 6th
+		testTx(model.TransactionDirectionCredit, 20, model.TransactionStateCompleted, *utc(6, 5), utc(6, 5), nil),
+		// Posted on the 4th and reversed in place on the 6th
+		testTx(model.TransactionDirectionCredit, 50, model.TransactionStateReversed, *utc(4, 12), utc(4, 12), utc(6, 18)),
+		// Still pending, so not a posting
+		testTx(model.TransactionDirectionCredit, 7, model.TransactionStatePending, *utc(5, 12), nil, nil),
+	}
+
+	windows, err := DailyWindows("2023-11-04", "2023-11-06", "America/New_York")
+	assert.NoError(t, err)
+
+	opening := model.AccountBalances{{Amount: money.NewAmount(10), Currency: "USD"}}
+	days, err := DailyBalances([]string{"USD", "EUR"}, opening, txs, windows)
+	assert.NoError(t, err)
+	assert.Len(t, days, 6)
+
+	type row struct{ date, currency, opening, credits, debits, closing string }
+	var got []row
+	for _, d := range days {
+		got = append(got, row{d.Date, d.Currency, d.Opening.String(), d.Credits.String(), d.Debits.String(), d.Closing.String()})
+	}
+
+	assert.Equal(t, []row{
+		{"2023-11-04", "USD", "10", "150", "0", "160"},
+		{"2023-11-04", "EUR", "0", "0", "0", "0"},
+		{"2023-11-05", "USD", "160", "0", "30", "130"},
+		{"2023-11-05", "EUR", "0", "0", "0", "0"},
+		{"2023-11-06", "USD", "130", "20", "50", "100"},
+		{"2023-11-06", "EUR", "0", "0", "0", "0"},
+	}, got)
+}
+
+func TestDailyBalancesOverdrawn(t *testing.T) {
+	txs := []model.Transaction{
+		testTx(model.TransactionDirectionDebit, 30, model.TransactionStateCompleted, *at(1), at(1), nil),
+	}
+
+	windows, err := DailyWindows("2023-05-01", "2023-05-01", "UTC")
+	assert.NoError(t, err)
+
+	_, err = DailyBalances([]string{"USD"}, model.AccountBalances{}, txs, windows)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/partyscript/bledger/internal/model"
@@ -25,6 +26,18 @@ var (
 		Message: "idempotency key is invalid",
 	}
 
+	// BLedgerUnauthorizedError is an error used to show a request lacked valid credentials
+	BLedgerUnauthorizedError = StandardSentinelError{
+		Status:  http.StatusUnauthorized,
+		Message: "unauthorized",
+	}
+
+	// BLedgerForbiddenError is an error used to show a request isn't allowed at all
+	BLedgerForbiddenError = StandardSentinelError{
+		Status:  http.StatusForbidden,
+		Message: "forbidden",
+	}
+
 	// BLedgerNotFoundError is an error used to show a request was invalid
 	BLedgerNotFoundError = StandardSentinelError{
 		Status:  http.StatusNotFound,
@@ -36,6 +49,28 @@ var (
 		Status:  http.StatusInternalServerError,
 		Message: "internal server error",
 	}
+
+	// BLedgerConflictError is an error used to show a request conflicts with the
+	// current state of a resource, e.g. an illegal state transition
+	BLedgerConflictError = StandardSentinelError{
+		Status:  http.StatusConflict,
+		Message: "conflict with current state",
+	}
+
+	// BLedgerPreconditionFailedError is an error used to show a request's If-Match
+	// header didn't match the resource's current version
+	BLedgerPreconditionFailedError = StandardSentinelError{
+		Status:  http.StatusPreconditionFailed,
+		Message: "precondition failed",
+	}
+
+	// ErrIllegalTransition is wrapped by controller errors for a state change
+	// that isn't allowed from the resource's current state
+	ErrIllegalTransition = errors.New("illegal state transition")
+
+	// ErrPreconditionFailed is wrapped by controller errors when a request's If-Match
+	// header doesn't match the version found under the row lock
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
 // APIError is an interface for accessing and implementing a client error
@@ -60,3 +95,15 @@ func WrapError(errMsg string, sentinel StandardSentinelError, av model.APIVersio
 func WrapAPIError(errMsg string, sentinel StandardSentinelError, av model.APIVersion) (int, model.StandardErrorResponse) {
 	return sentinel.Status, model.StandardErrorResponse{InternalErrMsg: errMsg, Error: sentinel, APIVersion: av}
 }
+
+// SentinelFor returns the sentinel error matching a controller error, or fallback
+// when the error has no more specific sentinel
+func SentinelFor(err error, fallback StandardSentinelError) StandardSentinelError {
+	if errors.Is(err, ErrIllegalTransition) {
+		return BLedgerConflictError
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		return BLedgerPreconditionFailedError
+	}
+	return fallback
+}
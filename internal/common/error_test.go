@@ -1,6 +1,8 @@
 package common
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/partyscript/bledger/internal/model"
@@ -61,3 +63,13 @@ func TestBadRequestWrappedAPIError(t *testing.T) {
 	assert.Equal(t, err.Error.(StandardSentinelError).Status, 400)
 
 }
+
+func TestSentinelFor(t *testing.T) {
+	illegal := fmt.Errorf("%w: cannot void a COMPLETED transaction", ErrIllegalTransition)
+	assert.Equal(t, BLedgerConflictError, SentinelFor(illegal, BLedgerBadRequestError))
+	assert.Equal(t, 409, SentinelFor(illegal, BLedgerBadRequestError).Status)
+	assert.Equal(t, BLedgerBadRequestError, SentinelFor(errors.New("insufficient funds"), BLedgerBadRequestError))
+
+	stale := fmt.Errorf("%w: current version is \"2\"", ErrPreconditionFailed)
+	assert.Equal(t, 412, SentinelFor(stale, BLedgerBadRequestError).Status)
+}
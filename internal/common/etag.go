@@ -0,0 +1,92 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatchHeader is the header carrying the entity tags a mutating request expects
+const IfMatchHeader = "If-Match"
+
+// ETag formats a resource version as a strong entity tag
+func ETag(version uint) string {
+	return fmt.Sprintf("%q", fmt.Sprint(version))
+}
+
+// Precondition is a parsed If-Match header. The zero value is a request without one,
+// which matches any version.
+type Precondition struct {
+	set  bool
+	any  bool
+	tags []string
+}
+
+// ParseIfMatch parses an If-Match header value
+func ParseIfMatch(header string) Precondition {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return Precondition{}
+	}
+
+	p := Precondition{set: true}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			p.any = true
+			continue
+		}
+		p.tags = append(p.tags, tag)
+	}
+
+	return p
+}
+
+// IsSet reports whether the request carried an If-Match header
+func (p Precondition) IsSet() bool {
+	return p.set
+}
+
+// IfMatch returns the parsed If-Match header of a request
+func IfMatch(c *gin.Context) Precondition {
+	return ParseIfMatch(c.GetHeader(IfMatchHeader))
+}
+
+// Check returns an ErrPreconditionFailed unless the resource's current version
+// matches the header. If-Match uses strong comparison, so weak tags never match.
+func (p Precondition) Check(version uint) error {
+	if !p.set || p.any {
+		return nil
+	}
+
+	current := ETag(version)
+	for _, tag := range p.tags {
+		if tag == current {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: current version is %v", ErrPreconditionFailed, current)
+}
+
+// CheckMissing returns an ErrPreconditionFailed if the request carried an If-Match
+// header for a resource that doesn't exist yet
+func (p Precondition) CheckMissing() error {
+	if !p.set {
+		return nil
+	}
+
+	return fmt.Errorf("%w: resource has no current version", ErrPreconditionFailed)
+}
+
+// CheckUnsupported returns an ErrPreconditionFailed if the request carried an If-Match
+// header on a route that writes many resources at once, where there is no single
+// version for it to match
+func (p Precondition) CheckUnsupported() error {
+	if !p.set {
+		return nil
+	}
+
+	return fmt.Errorf("%w: If-Match isn't supported on a request that writes many resources", ErrPreconditionFailed)
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	assert.Equal(t, `"1"`, ETag(1))
+	assert.Equal(t, `"42"`, ETag(42))
+}
+
+func TestPreconditionCheck(t *testing.T) {
+	testCases := []struct {
+		header  string
+		version uint
+		ok      bool
+	}{
+		{header: "", version: 3, ok: true},
+		{header: `"3"`, version: 3, ok: true},
+		{header: `"2"`, version: 3, ok: false},
+		{header: `"1", "3"`, version: 3, ok: true},
+		{header: `*`, version: 3, ok: true},
+		{header: `W/"3"`, version: 3, ok: false},
+		{header: `3`, version: 3, ok: false},
+	}
+
+	for _, tc := range testCases {
+		err := ParseIfMatch(tc.header).Check(tc.version)
+		if tc.ok {
+			assert.NoError(t, err, tc.header)
+		} else {
+			assert.True(t, errors.Is(err, ErrPreconditionFailed), tc.header)
+		}
+	}
+}
+
+func TestPreconditionCheckMissing(t *testing.T) {
+	assert.False(t, ParseIfMatch("").IsSet())
+	assert.NoError(t, ParseIfMatch("").CheckMissing())
+	assert.True(t, errors.Is(ParseIfMatch(`"1"`).CheckMissing(), ErrPreconditionFailed))
+	assert.True(t, errors.Is(ParseIfMatch(`*`).CheckMissing(), ErrPreconditionFailed))
+}
+
+func TestPreconditionCheckUnsupported(t *testing.T) {
+	assert.NoError(t, ParseIfMatch("").CheckUnsupported())
+	assert.True(t, errors.Is(ParseIfMatch(`"1"`).CheckUnsupported(), ErrPreconditionFailed))
+	assert.True(t, errors.Is(ParseIfMatch(`*`).CheckUnsupported(), ErrPreconditionFailed))
+}
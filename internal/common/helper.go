@@ -5,6 +5,7 @@ import (
 
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/partyscript/bledger/internal/model"
@@ -20,7 +21,7 @@ func GenIdempotencyKey(
 		return "", errors.New("account id cannot be empty")
 	}
 
-	if money.Amount == 0 {
+	if money.Amount.IsZero() {
 		return "", errors.New("money amount cannot be empty")
 	}
 
@@ -44,7 +45,34 @@ func CheckID(c *gin.Context) (string, error) {
 	return id, nil
 }
 
-// IsCompletableState checks if transaction is completable
-func IsCompletableState(tx model.Transaction) bool {
-	return tx.State == model.TransactionStatePending
+// Actor returns who is making a request, from the actor header
+func Actor(c *gin.Context) string {
+	actor := c.GetHeader(ActorHeader)
+	if actor == "" {
+		return DefaultActor
+	}
+	return actor
+}
+
+// IsExpired checks if an expiry has passed at now. A nil expiry never passes.
+func IsExpired(expiresAt *time.Time, now time.Time) bool {
+	return expiresAt != nil && !now.Before(*expiresAt)
+}
+
+// ExpiresAt resolves a requested expiry, falling back to now plus the default ttl.
+// A requested expiry must be in the future.
+func ExpiresAt(requested *time.Time, ttl time.Duration, now time.Time) (*time.Time, error) {
+	if requested != nil {
+		if !requested.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		return requested, nil
+	}
+
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	expiresAt := now.Add(ttl)
+	return &expiresAt, nil
 }
@@ -1,9 +1,13 @@
 package common
 
 import (
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,13 +21,13 @@ func TestGenIdempotencyKey(t *testing.T) {
 	}{
 		{
 			accountID: "123",
-			money:     model.TransactionMoney{Amount: 100, Currency: "USD"},
+			money:     model.TransactionMoney{Amount: money.NewAmount(100), Currency: "USD"},
 			direction: "debit",
 			expected:  "MTIzLTEwMC1VU0QtZGViaXQ=",
 		},
 		{
 			accountID: "123",
-			money:     model.TransactionMoney{Amount: 100, Currency: "USD"},
+			money:     model.TransactionMoney{Amount: money.NewAmount(100), Currency: "USD"},
 			direction: "credit",
 			expected:  "MTIzLTEwMC1VU0QtY3JlZGl0",
 		},
@@ -36,3 +40,44 @@ func TestGenIdempotencyKey(t *testing.T) {
 		assert.Equal(t, tc.expected, actual)
 	}
 }
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Second)
+	future := now.Add(time.Second)
+
+	assert.False(t, IsExpired(nil, now))
+	assert.True(t, IsExpired(&past, now))
+	assert.True(t, IsExpired(&now, now))
+	assert.False(t, IsExpired(&future, now))
+}
+
+func TestExpiresAt(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	expiresAt, err := ExpiresAt(nil, time.Hour, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), *expiresAt)
+
+	expiresAt, err = ExpiresAt(&future, time.Hour, now)
+	assert.NoError(t, err)
+	assert.Equal(t, future, *expiresAt)
+
+	expiresAt, err = ExpiresAt(nil, 0, now)
+	assert.NoError(t, err)
+	assert.Nil(t, expiresAt)
+
+	_, err = ExpiresAt(&past, time.Hour, now)
+	assert.Error(t, err)
+}
+
+func TestActor(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/transactions/1/void", nil)
+	assert.Equal(t, DefaultActor, Actor(c))
+
+	c.Request.Header.Set(ActorHeader, "user:42")
+	assert.Equal(t, "user:42", Actor(c))
+}
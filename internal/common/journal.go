@@ -0,0 +1,67 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// ValidateBalancedPostings checks that every posting of a journal entry is well formed
+// and that debits and credits sum to zero in every currency
+func ValidateBalancedPostings(postings []model.CreatePostingRequest) error {
+	if len(postings) < 2 {
+		return errors.New("journal entry needs at least two postings")
+	}
+
+	debits := map[string]money.Amount{}
+	credits := map[string]money.Amount{}
+
+	for i, p := range postings {
+		if p.AccountID == "" {
+			return fmt.Errorf("posting %v: account id cannot be empty", i)
+		}
+
+		if p.Money.Amount.IsZero() {
+			return fmt.Errorf("posting %v: money amount cannot be empty", i)
+		}
+
+		if p.Money.Currency == "" {
+			return fmt.Errorf("posting %v: money currency cannot be empty", i)
+		}
+
+		var err error
+		switch p.Direction {
+		case model.TransactionDirectionDebit:
+			debits[p.Money.Currency], err = debits[p.Money.Currency].Add(p.Money.Amount)
+		case model.TransactionDirectionCredit:
+			credits[p.Money.Currency], err = credits[p.Money.Currency].Add(p.Money.Amount)
+		default:
+			return fmt.Errorf("posting %v: invalid direction %v", i, p.Direction)
+		}
+		if err != nil {
+			return fmt.Errorf("posting %v: %w", i, err)
+		}
+	}
+
+	currencies := make([]string, 0, len(debits)+len(credits))
+	for c := range debits {
+		currencies = append(currencies, c)
+	}
+	for c := range credits {
+		if _, ok := debits[c]; !ok {
+			currencies = append(currencies, c)
+		}
+	}
+	sort.Strings(currencies)
+
+	for _, c := range currencies {
+		if debits[c].Cmp(credits[c]) != 0 {
+			return fmt.Errorf("postings are not balanced in %v: debits %v, credits %v", c, debits[c], credits[c])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func posting(acct string, dir model.TransactionDirection, amt uint64, cur string) model.CreatePostingRequest {
+	return model.CreatePostingRequest{
+		AccountID: acct,
+		Direction: dir,
+		Money:     model.TransactionMoney{Amount: money.NewAmount(amt), Currency: cur},
+	}
+}
+
+func TestValidateBalancedPostings(t *testing.T) {
+	testCases := []struct {
+		name     string
+		postings []model.CreatePostingRequest
+		valid    bool
+	}{
+		{
+			name: "split payment",
+			postings: []model.CreatePostingRequest{
+				posting("customer", model.TransactionDirectionDebit, 1000, "USD"),
+				posting("merchant", model.TransactionDirectionCredit, 900, "USD"),
+				posting("fees", model.TransactionDirectionCredit, 70, "USD"),
+				posting("tax", model.TransactionDirectionCredit, 30, "USD"),
+			},
+			valid: true,
+		},
+		{
+			name: "balanced per currency",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 100, "USD"),
+				posting("b", model.TransactionDirectionCredit, 100, "USD"),
+				posting("c", model.TransactionDirectionDebit, 50, "EUR"),
+				posting("d", model.TransactionDirectionCredit, 50, "EUR"),
+			},
+			valid: true,
+		},
+		{
+			name: "unbalanced",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 100, "USD"),
+				posting("b", model.TransactionDirectionCredit, 99, "USD"),
+			},
+			valid: false,
+		},
+		{
+			name: "balanced in total but not per currency",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 100, "USD"),
+				posting("b", model.TransactionDirectionCredit, 100, "EUR"),
+			},
+			valid: false,
+		},
+		{
+			name: "single leg",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 100, "USD"),
+			},
+			valid: false,
+		},
+		{
+			name: "invalid direction",
+			postings: []model.CreatePostingRequest{
+				posting("a", "SIDEWAYS", 100, "USD"),
+				posting("b", model.TransactionDirectionCredit, 100, "USD"),
+			},
+			valid: false,
+		},
+		{
+			name: "beyond uint64",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 18446744073709551615, "ETH"),
+				posting("b", model.TransactionDirectionDebit, 18446744073709551615, "ETH"),
+				posting("c", model.TransactionDirectionCredit, 18446744073709551615, "ETH"),
+				posting("d", model.TransactionDirectionCredit, 18446744073709551615, "ETH"),
+			},
+			valid: true,
+		},
+		{
+			name: "zero amount",
+			postings: []model.CreatePostingRequest{
+				posting("a", model.TransactionDirectionDebit, 0, "USD"),
+				posting("b", model.TransactionDirectionCredit, 0, "USD"),
+			},
+			valid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateBalancedPostings(tc.postings)
+		if tc.valid {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.Error(t, err, tc.name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/partyscript/bledger/internal/model"
+)
+
+// TransactionEffect is a balance change applied when a transaction moves between states
+type TransactionEffect string
+
+const (
+	// TransactionEffectReserve holds a debit's funds out of the available balance.
+	// A credit is only checked against the account.
+	TransactionEffectReserve TransactionEffect = "RESERVE"
+	// TransactionEffectRelease returns any funds the transaction reserved
+	TransactionEffectRelease TransactionEffect = "RELEASE"
+	// TransactionEffectPost applies the transaction to the posted balance
+	TransactionEffectPost TransactionEffect = "POST"
+)
+
+// transactionTransitions lists every legal transaction state change and the balance
+// effects applied, in order, when making it. The empty state is a transaction that
+// hasn't been written yet. A completed transaction is final; it is reversed by
+// creating a separate, opposite transaction.
+var transactionTransitions = map[model.TransactionState]map[model.TransactionState][]TransactionEffect{
+	"": {
+		model.TransactionStatePending:   {TransactionEffectReserve},
+		model.TransactionStateCompleted: {TransactionEffectPost},
+		model.TransactionStateFailed:    {},
+	},
+	model.TransactionStatePending: {
+		model.TransactionStateCompleted: {TransactionEffectRelease, TransactionEffectPost},
+		model.TransactionStateFailed:    {TransactionEffectRelease},
+		model.TransactionStateVoided:    {TransactionEffectRelease},
+		model.TransactionStateExpired:   {TransactionEffectRelease},
+	},
+}
+
+// TransactionTransition returns the balance effects of moving a transaction from one
+// state to another, or an ErrIllegalTransition if the state machine has no such edge
+func TransactionTransition(from model.TransactionState, to model.TransactionState) ([]TransactionEffect, error) {
+	effects, ok := transactionTransitions[from][to]
+	if !ok {
+		if from == "" {
+			return nil, fmt.Errorf("%w: cannot create a transaction as %v", ErrIllegalTransition, to)
+		}
+		return nil, fmt.Errorf("%w: cannot move a %v transaction to %v", ErrIllegalTransition, from, to)
+	}
+
+	return effects, nil
+}
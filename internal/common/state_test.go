@@ -0,0 +1,54 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionTransition(t *testing.T) {
+	testCases := []struct {
+		from    model.TransactionState
+		to      model.TransactionState
+		effects []TransactionEffect
+	}{
+		{from: "", to: model.TransactionStatePending, effects: []TransactionEffect{TransactionEffectReserve}},
+		{from: "", to: model.TransactionStateCompleted, effects: []TransactionEffect{TransactionEffectPost}},
+		{from: "", to: model.TransactionStateFailed, effects: []TransactionEffect{}},
+		{from: model.TransactionStatePending, to: model.TransactionStateCompleted, effects: []TransactionEffect{TransactionEffectRelease, TransactionEffectPost}},
+		{from: model.TransactionStatePending, to: model.TransactionStateVoided, effects: []TransactionEffect{TransactionEffectRelease}},
+		{from: model.TransactionStatePending, to: model.TransactionStateFailed, effects: []TransactionEffect{TransactionEffectRelease}},
+		{from: model.TransactionStatePending, to: model.TransactionStateExpired, effects: []TransactionEffect{TransactionEffectRelease}},
+	}
+
+	for _, tc := range testCases {
+		effects, err := TransactionTransition(tc.from, tc.to)
+		assert.NoError(t, err, "%v -> %v", tc.from, tc.to)
+		assert.Equal(t, tc.effects, effects, "%v -> %v", tc.from, tc.to)
+	}
+}
+
+func TestTransactionTransitionIllegal(t *testing.T) {
+	testCases := []struct {
+		from model.TransactionState
+		to   model.TransactionState
+	}{
+		{from: "", to: model.TransactionStateReversed},
+		{from: model.TransactionStatePending, to: model.TransactionStateReversed},
+		{from: model.TransactionStatePending, to: model.TransactionStatePending},
+		{from: model.TransactionStateCompleted, to: model.TransactionStateVoided},
+		{from: model.TransactionStateCompleted, to: model.TransactionStateCompleted},
+		{from: model.TransactionStateCompleted, to: model.TransactionStateReversed},
+		{from: model.TransactionStateFailed, to: model.TransactionStateCompleted},
+		{from: model.TransactionStateVoided, to: model.TransactionStatePending},
+		{from: model.TransactionStateExpired, to: model.TransactionStateCompleted},
+		{from: model.TransactionStateReversed, to: model.TransactionStateCompleted},
+	}
+
+	for _, tc := range testCases {
+		_, err := TransactionTransition(tc.from, tc.to)
+		assert.True(t, errors.Is(err, ErrIllegalTransition), "%v -> %v", tc.from, tc.to)
+	}
+}
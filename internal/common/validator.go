@@ -0,0 +1,49 @@
+package common
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// RegisterValidators adds the custom binding tags used by request models:
+// `currency` accepts known ISO 4217 currency codes and `amount_gt0` accepts
+// amounts above zero
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+
+	// Tags on struct fields are skipped unless the validator can see the struct as a
+	// plain value, so amounts are validated as their decimal string
+	v.RegisterCustomTypeFunc(amountValue, money.Amount{})
+
+	err := v.RegisterValidation("currency", ValidCurrency)
+	if err != nil {
+		return err
+	}
+	return v.RegisterValidation("amount_gt0", PositiveAmount)
+}
+
+// ValidCurrency is a binding validator that accepts known ISO 4217 currency codes
+func ValidCurrency(fl validator.FieldLevel) bool {
+	return money.ValidateCurrency(fl.Field().String()) == nil
+}
+
+// PositiveAmount is a binding validator that accepts amounts above zero. A missing
+// amount decodes as zero, so it is refused too.
+func PositiveAmount(fl validator.FieldLevel) bool {
+	amount, err := money.ParseAmount(fl.Field().String())
+	return err == nil && !amount.IsZero()
+}
+
+func amountValue(v reflect.Value) interface{} {
+	amount, ok := v.Interface().(money.Amount)
+	if !ok {
+		return nil
+	}
+	return amount.String()
+}
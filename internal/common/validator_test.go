@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmountValidation(t *testing.T) {
+	assert.NoError(t, RegisterValidators())
+
+	bind := func(body string, obj interface{}) error {
+		return binding.JSON.BindBody([]byte(body), obj)
+	}
+
+	tx := `"memo":"m","direction":"CREDIT","account_id":"a"`
+	assert.NoError(t, bind(`{"money":{"amount":"1","currency":"USD"},`+tx+`}`, &model.CreateTransactionRequest{}))
+	assert.NoError(t, bind(`{"money":{"amount":"115792089237316195423570985008687907853269984665640564039457584007913129639935","currency":"ETH"},`+tx+`}`, &model.CreateTransactionRequest{}))
+	assert.Error(t, bind(`{"money":{"amount":"0","currency":"USD"},`+tx+`}`, &model.CreateTransactionRequest{}))
+	assert.Error(t, bind(`{"money":{"amount":0,"currency":"USD"},`+tx+`}`, &model.CreateTransactionRequest{}))
+	assert.Error(t, bind(`{"money":{"currency":"USD"},`+tx+`}`, &model.CreateTransactionRequest{}))
+
+	// Every request carrying money goes through the same check
+	assert.Error(t, bind(`{"money":{"amount":"0","currency":"USD"},"from_account_id":"a","to_account_id":"b"}`, &model.CreateTransferRequest{}))
+	assert.Error(t, bind(`{"money":{"currency":"USD"},"account_id":"a"}`, &model.CreateHoldRequest{}))
+	assert.Error(t, bind(`{"mode":"ATOMIC","items":[{"money":{"amount":"1","currency":"USD"},`+tx+`},{"money":{"amount":"0","currency":"USD"},`+tx+`}]}`, &model.CreateTransactionBatchRequest{}))
+	assert.Error(t, bind(`{"postings":[{"money":{"amount":"0","currency":"USD"},"direction":"DEBIT","account_id":"a"},{"money":{"amount":"0","currency":"USD"},"direction":"CREDIT","account_id":"b"}]}`, &model.CreateJournalEntryRequest{}))
+
+	assert.NoError(t, bind(`{"amount":"5"}`, &model.CreateRefundRequest{}))
+	assert.Error(t, bind(`{"amount":"0"}`, &model.CreateRefundRequest{}))
+	assert.Error(t, bind(`{}`, &model.CreateRefundRequest{}))
+
+	// Leaving out a capture amount captures the whole hold, but zero is refused
+	assert.NoError(t, bind(`{}`, &model.CaptureHoldRequest{}))
+	assert.Error(t, bind(`{"amount":"0"}`, &model.CaptureHoldRequest{}))
+}
@@ -0,0 +1,129 @@
+package common
+
+import (
+	"encoding/json"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// ReplayBalances rebuilds an account's balances from its history alone. The posted
+// amount is every completed credit less every completed debit; transactions reversed
+// in place by older versions cancel out. The held amount is every pending debit still
+// reserving funds plus every active hold. Balances come back in the order of
+// currencies, followed by any other currency the history touches. A history that
+// debits a currency more than it credits it has no balance to rebuild; its posted
+// amount is left at zero and the shortfall is returned by currency instead.
+func ReplayBalances(currencies []string, txs []model.Transaction, holds []model.Hold) (model.AccountBalances, map[string]money.Amount, error) {
+	bal := model.AccountBalances{}
+	for _, c := range currencies {
+		if bal.Find(c) == nil {
+			bal = append(bal, model.AccountMoney{Currency: c})
+		}
+	}
+
+	bucket := func(currency string) *model.AccountMoney {
+		if m := bal.Find(currency); m != nil {
+			return m
+		}
+		bal = append(bal, model.AccountMoney{Currency: currency})
+		return &bal[len(bal)-1]
+	}
+
+	credits := map[string]money.Amount{}
+	debits := map[string]money.Amount{}
+
+	for _, tx := range txs {
+		var txMoney model.TransactionMoney
+		err := json.Unmarshal(tx.Money, &txMoney)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case tx.State == model.TransactionStateCompleted && tx.Direction == model.TransactionDirectionCredit:
+			credits[txMoney.Currency], err = credits[txMoney.Currency].Add(txMoney.Amount)
+		case tx.State == model.TransactionStateCompleted && tx.Direction == model.TransactionDirectionDebit:
+			debits[txMoney.Currency], err = debits[txMoney.Currency].Add(txMoney.Amount)
+		case tx.State == model.TransactionStatePending && tx.FundsHeld:
+			m := bucket(txMoney.Currency)
+			m.Held, err = m.Held.Add(txMoney.Amount)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		bucket(txMoney.Currency)
+	}
+
+	for _, h := range holds {
+		if h.State != model.HoldStateActive {
+			continue
+		}
+
+		var holdMoney model.TransactionMoney
+		err := json.Unmarshal(h.Money, &holdMoney)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m := bucket(holdMoney.Currency)
+		m.Held, err = m.Held.Add(holdMoney.Amount)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	shortfalls := map[string]money.Amount{}
+	for i := range bal {
+		c := bal[i].Currency
+		posted, err := credits[c].Sub(debits[c])
+		if err != nil {
+			shortfalls[c], err = debits[c].Sub(credits[c])
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		bal[i].Amount = posted
+	}
+
+	return bal, shortfalls, nil
+}
+
+// DiffBalances returns a drift for every currency whose stored posted or held amount
+// differs from the expected one. A currency missing from stored counts as zero. A
+// currency with a shortfall is always a drift, marked overdrawn, since no stored
+// balance can match a history that debits more than it credits.
+func DiffBalances(accountID string, stored model.AccountBalances, expected model.AccountBalances, shortfalls map[string]money.Amount) []model.BalanceDrift {
+	drifts := []model.BalanceDrift{}
+
+	for _, want := range expected {
+		var have model.AccountMoney
+		if m := stored.Find(want.Currency); m != nil {
+			have = *m
+		}
+
+		shortfall, overdrawn := shortfalls[want.Currency]
+		if !overdrawn && have.Amount.Cmp(want.Amount) == 0 && have.Held.Cmp(want.Held) == 0 {
+			continue
+		}
+
+		drift := model.BalanceDrift{
+			AccountID:      accountID,
+			Currency:       want.Currency,
+			StoredPosted:   have.Amount,
+			ExpectedPosted: want.Amount,
+			StoredHeld:     have.Held,
+			ExpectedHeld:   want.Held,
+		}
+		if overdrawn {
+			drift.Overdrawn = true
+			drift.Shortfall = &shortfall
+		}
+		drifts = append(drifts, drift)
+	}
+
+	return drifts
+}
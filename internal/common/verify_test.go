@@ -0,0 +1,83 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestReplayBalances(t *testing.T) {
+	pending := testTx(model.TransactionDirectionDebit, 20, model.TransactionStatePending, *at(3), nil, nil)
+	pending.FundsHeld = true
+
+	eur := testTx(model.TransactionDirectionCredit, 9, model.TransactionStateCompleted, *at(1), at(1), nil)
+	eur.Money = datatypes.JSON(`{"amount":"9","currency":"EUR"}`)
+
+	txs := []model.Transaction{
+		testTx(model.TransactionDirectionCredit, 100, model.TransactionStateCompleted, *at(1), at(1), nil),
+		testTx(model.TransactionDirectionDebit, 30, model.TransactionStateCompleted, *at(2), at(2), nil),
+		testTx(model.TransactionDirectionCredit, 50, model.TransactionStateReversed, *at(2), at(2), at(3)),
+		testTx(model.TransactionDirectionCredit, 7, model.TransactionStatePending, *at(3), nil, nil),
+		testTx(model.TransactionDirectionDebit, 1000, model.TransactionStateFailed, *at(3), nil, nil),
+		pending,
+		eur,
+	}
+
+	holds := []model.Hold{
+		{Money: datatypes.JSON(`{"amount":"5","currency":"USD"}`), State: model.HoldStateActive},
+		{Money: datatypes.JSON(`{"amount":"500","currency":"USD"}`), State: model.HoldStateCaptured},
+	}
+
+	bal, shortfalls, err := ReplayBalances([]string{"USD", "GBP"}, txs, holds)
+	assert.NoError(t, err)
+	assert.Empty(t, shortfalls)
+	assert.Equal(t, []string{"USD", "GBP", "EUR"}, bal.Currencies())
+	assert.Equal(t, "70", bal.Find("USD").Amount.String())
+	assert.Equal(t, "25", bal.Find("USD").Held.String())
+	assert.Equal(t, "0", bal.Find("GBP").Amount.String())
+	assert.Equal(t, "9", bal.Find("EUR").Amount.String())
+
+	// A history that debits more than it credits has no balance, rather than a zero one
+	overdrawn := append(txs, testTx(model.TransactionDirectionDebit, 90, model.TransactionStateCompleted, *at(4), at(4), nil))
+	bal, shortfalls, err = ReplayBalances([]string{"USD", "GBP"}, overdrawn, holds)
+	assert.NoError(t, err)
+	assert.Len(t, shortfalls, 1)
+	assert.Equal(t, "20", shortfalls["USD"].String())
+	assert.Equal(t, "0", bal.Find("USD").Amount.String())
+	assert.Equal(t, "25", bal.Find("USD").Held.String())
+}
+
+func TestDiffBalances(t *testing.T) {
+	stored := model.AccountBalances{
+		{Amount: money.NewAmount(70), Held: money.NewAmount(25), Currency: "USD"},
+		{Amount: money.NewAmount(3), Currency: "GBP"},
+	}
+	expected := model.AccountBalances{
+		{Amount: money.NewAmount(70), Held: money.NewAmount(25), Currency: "USD"},
+		{Amount: money.NewAmount(0), Currency: "GBP"},
+		{Amount: money.NewAmount(9), Currency: "EUR"},
+	}
+
+	drifts := DiffBalances("acct", stored, expected, nil)
+	assert.Len(t, drifts, 2)
+	assert.Equal(t, "GBP", drifts[0].Currency)
+	assert.Equal(t, "3", drifts[0].StoredPosted.String())
+	assert.Equal(t, "0", drifts[0].ExpectedPosted.String())
+	assert.Equal(t, "EUR", drifts[1].Currency)
+	assert.Equal(t, "0", drifts[1].StoredPosted.String())
+	assert.Equal(t, "9", drifts[1].ExpectedPosted.String())
+	assert.Equal(t, "acct", drifts[1].AccountID)
+
+	assert.Empty(t, DiffBalances("acct", expected, expected, nil))
+
+	// An overdrawn currency drifts even when the stored balance reads as zero
+	drifts = DiffBalances("acct", expected, expected, map[string]money.Amount{"GBP": money.NewAmount(4)})
+	assert.Len(t, drifts, 1)
+	assert.Equal(t, "GBP", drifts[0].Currency)
+	assert.True(t, drifts[0].Overdrawn)
+	assert.Equal(t, "4", drifts[0].Shortfall.String())
+	assert.False(t, drifts[0].Repaired)
+}
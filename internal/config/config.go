@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/partyscript/bledger/internal/model"
@@ -12,6 +13,11 @@ type GlobalConfig struct {
 	Cache       *CacheConfig
 	Environment *EnvironmentConfig
 	DB          *DBConfig
+	FX          *FXConfig
+	Expiry      *ExpiryConfig
+	Admin       *AdminConfig
+	Snapshot    *SnapshotConfig
+	Batch       *BatchConfig
 }
 
 // EnvironmentConfig is a config to get the environment
@@ -31,11 +37,53 @@ type CacheConfig struct {
 	Password string `envconfig:"CACHE_PASSWORD"`
 }
 
+// FXConfig is a config for the foreign exchange rate provider.
+// Provider is either "static", which reads Rates, or "file", which reads RatesFile.
+type FXConfig struct {
+	Provider  string            `envconfig:"FX_PROVIDER" default:"static"`
+	Rates     map[string]string `envconfig:"FX_RATES"`
+	RatesFile string            `envconfig:"FX_RATES_FILE"`
+}
+
+// ExpiryConfig is a config for expiring stale pending transactions and holds.
+// PendingTTL is the expiry given to anything created without one.
+type ExpiryConfig struct {
+	PendingTTL    time.Duration `envconfig:"PENDING_TTL" default:"168h"`
+	SweepInterval time.Duration `envconfig:"EXPIRY_SWEEP_INTERVAL" default:"1m"`
+	SweepBatch    int           `envconfig:"EXPIRY_SWEEP_BATCH" default:"100"`
+}
+
+// SnapshotConfig is a config for periodic balance snapshots. An account is snapshotted
+// once it has changed Every times, or MaxAge after its last snapshot if it changed at all.
+// Accounts are checked every Interval; a non-positive Interval disables snapshots.
+type SnapshotConfig struct {
+	Every    uint          `envconfig:"SNAPSHOT_EVERY" default:"1000"`
+	MaxAge   time.Duration `envconfig:"SNAPSHOT_MAX_AGE" default:"24h"`
+	Interval time.Duration `envconfig:"SNAPSHOT_INTERVAL" default:"1m"`
+	Batch    int           `envconfig:"SNAPSHOT_BATCH" default:"100"`
+}
+
+// BatchConfig is a config for batch transaction submission and CSV imports
+type BatchConfig struct {
+	MaxItems      int `envconfig:"BATCH_MAX_ITEMS" default:"1000"`
+	MaxImportRows int `envconfig:"IMPORT_MAX_ROWS" default:"100000"`
+}
+
+// AdminConfig is a config for the admin endpoints. An empty Token disables them.
+type AdminConfig struct {
+	Token string `envconfig:"ADMIN_TOKEN"`
+}
+
 // NewGlobalConfig generates a new instance of GlobalConfig
 func NewGlobalConfig() (*GlobalConfig, error) {
 	var db DBConfig
 	var env EnvironmentConfig
 	var cache CacheConfig
+	var fx FXConfig
+	var expiry ExpiryConfig
+	var admin AdminConfig
+	var snapshot SnapshotConfig
+	var batch BatchConfig
 
 	err := envconfig.Process("DB", &db)
 	if err != nil {
@@ -52,9 +100,39 @@ func NewGlobalConfig() (*GlobalConfig, error) {
 		return nil, errors.New("redis config cannot be nil")
 	}
 
+	err = envconfig.Process("FX", &fx)
+	if err != nil {
+		return nil, errors.New("fx config is invalid")
+	}
+
+	err = envconfig.Process("EXPIRY", &expiry)
+	if err != nil || expiry.SweepBatch < 1 {
+		return nil, errors.New("expiry config is invalid")
+	}
+
+	err = envconfig.Process("ADMIN", &admin)
+	if err != nil {
+		return nil, errors.New("admin config is invalid")
+	}
+
+	err = envconfig.Process("SNAPSHOT", &snapshot)
+	if err != nil || snapshot.Batch < 1 {
+		return nil, errors.New("snapshot config is invalid")
+	}
+
+	err = envconfig.Process("BATCH", &batch)
+	if err != nil || batch.MaxItems < 1 || batch.MaxImportRows < 1 {
+		return nil, errors.New("batch config is invalid")
+	}
+
 	return &GlobalConfig{
 		Environment: &env,
 		DB:          &db,
 		Cache:       &cache,
+		FX:          &fx,
+		Expiry:      &expiry,
+		Admin:       &admin,
+		Snapshot:    &snapshot,
+		Batch:       &batch,
 	}, nil
 }
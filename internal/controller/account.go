@@ -1,16 +1,25 @@
 package controller
 
 import (
+	"database/sql"
 	"encoding/json"
+	"sort"
+	"time"
 
 	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
 	"github.com/partyscript/bledger/internal/config"
 	"github.com/partyscript/bledger/internal/db"
 	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/partyscript/bledger/internal/statement"
 	"go.uber.org/zap"
-	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
+// defaultCurrency is the currency an account holds when it does not declare any
+const defaultCurrency = "USD"
+
 // AccountController is the struct that the constructor implements
 type AccountController struct {
 	logger *zap.SugaredLogger
@@ -48,10 +57,34 @@ func (ac *AccountController) GetAccount(id string) (*model.Account, error) {
 }
 
 // CreateAccount creates a new account
-func (ac *AccountController) CreateAccount(acctReq model.CreateAccountRequest) (*model.Account, error) {
-	bal := model.AccountMoney{Currency: "USD"}
+func (ac *AccountController) CreateAccount(acctReq model.CreateAccountRequest, pre common.Precondition) (*model.Account, error) {
+	// A new account has no version for If-Match to match
+	err := pre.CheckMissing()
+	if err != nil {
+		return nil, err
+	}
 
-	amtToStore, err := json.Marshal(bal)
+	currencies := acctReq.Currencies
+	if acctReq.Currency != "" {
+		currencies = append([]string{acctReq.Currency}, currencies...)
+	}
+	if len(currencies) == 0 {
+		currencies = []string{defaultCurrency}
+	}
+
+	// Open an empty balance for every currency the account holds
+	bal := model.AccountBalances{}
+	for _, currency := range currencies {
+		err := money.ValidateCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+		if bal.Find(currency) == nil {
+			bal = append(bal, model.AccountMoney{Currency: currency})
+		}
+	}
+
+	amtToStore, err := bal.Stored()
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +92,8 @@ func (ac *AccountController) CreateAccount(acctReq model.CreateAccountRequest) (
 	acct := &model.Account{
 		Name:        acctReq.Name,
 		Description: acctReq.Description,
-		Balance:     datatypes.JSON(amtToStore),
+		Balance:     amtToStore,
+		Version:     1,
 	}
 
 	create := ac.db.Gorm.Create(acct)
@@ -69,3 +103,218 @@ func (ac *AccountController) CreateAccount(acctReq model.CreateAccountRequest) (
 
 	return acct, nil
 }
+
+// GetBalanceAsOf reconstructs an account's balance at a point in time from the nearest
+// earlier snapshot and the transactions after it, reporting pending amounts separately
+func (ac *AccountController) GetBalanceAsOf(id string, asOf time.Time) (*model.AccountBalanceResponse, error) {
+	acct, err := ac.GetAccount(id)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := latestSnapshot(ac.db.Gorm, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	return replayBalance(ac.db.Gorm, acct, base, asOf)
+}
+
+// GetDailyBalances returns an account's opening, closing, credit and debit totals for
+// each local day in windows, one row per day per currency
+func (ac *AccountController) GetDailyBalances(id string, windows []common.DayWindow) ([]model.DailyBalance, error) {
+	var txs []model.Transaction
+
+	acct, err := ac.GetAccount(id)
+	if err != nil {
+		return nil, err
+	}
+
+	start := windows[0].Start
+	end := windows[len(windows)-1].End
+
+	// The opening balance is everything posted before the first day starts
+	beforeStart := start.Add(-time.Nanosecond)
+	base, err := latestSnapshot(ac.db.Gorm, id, beforeStart)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := replayBalance(ac.db.Gorm, acct, base, beforeStart)
+	if err != nil {
+		return nil, err
+	}
+
+	find := ac.db.Gorm.
+		Where("account_id = ? AND created_at < ?", id, end).
+		Where("state IN ?", []model.TransactionState{
+			model.TransactionStateCompleted,
+			model.TransactionStateReversed,
+		}).
+		// Legacy REVERSED rows also move the balance on the day they were undone
+		Where(ac.db.Gorm.
+			Where("completed_at >= ? AND completed_at < ?", start, end).
+			Or("reversed_at >= ? AND reversed_at < ?", start, end).
+			Or("completed_at IS NULL").
+			Or("state = ? AND reversed_at IS NULL", model.TransactionStateReversed)).
+		Find(&txs)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return common.DailyBalances(opening.Balance.Currencies(), opening.Balance, txs, windows)
+}
+
+// postedAtSQL mirrors common.CompletedAt for rows written before completed_at existed
+const postedAtSQL = "COALESCE(completed_at, CASE WHEN state = 'REVERSED' THEN created_at ELSE updated_at END)"
+
+// StatementReader reads an account's statement from one database snapshot
+type StatementReader struct {
+	gdb *gorm.DB
+}
+
+// ReadStatement calls fn with a StatementReader over a read-only REPEATABLE READ
+// transaction, so the balances and the postings it reads all come from the same
+// snapshot and a posting that lands part way through can't make them disagree
+func (ac *AccountController) ReadStatement(fn func(r StatementReader) error) error {
+	tx := ac.db.Gorm.Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err := fn(StatementReader{gdb: tx})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Opening returns an account and its posted balances just before start
+func (r StatementReader) Opening(id string, start time.Time) (*model.Account, model.AccountBalances, error) {
+	acct := new(model.Account)
+	err := r.gdb.First(acct, &model.Account{ID: id}).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	beforeStart := start.Add(-time.Nanosecond)
+	base, err := latestSnapshot(r.gdb, id, beforeStart)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opening, err := replayBalance(r.gdb, acct, base, beforeStart)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return acct, opening.Balance, nil
+}
+
+// Entries calls fn with every posting made on an account in [start, end), oldest
+// first. Postings are read from a cursor rather than loaded up front, so a statement
+// for a busy account never sits in memory whole.
+func (r StatementReader) Entries(id string, start time.Time, end time.Time, fn func(statement.Entry) error) error {
+	// Legacy in-place reversals post a second, opposite movement when they were
+	// reversed. They're rare, so they are loaded up front and merged into the stream.
+	var reversed []model.Transaction
+	err := r.gdb.
+		Where("account_id = ? AND state = ?", id, model.TransactionStateReversed).
+		Where("COALESCE(reversed_at, updated_at) >= ? AND COALESCE(reversed_at, updated_at) < ?", start, end).
+		Find(&reversed).Error
+	if err != nil {
+		return err
+	}
+
+	var undone []statement.Entry
+	for _, t := range reversed {
+		for _, p := range common.Postings(t) {
+			if p.Reversal {
+				e, err := statementEntry(t, p)
+				if err != nil {
+					return err
+				}
+				undone = append(undone, e)
+			}
+		}
+	}
+	sort.SliceStable(undone, func(i, j int) bool { return undone[i].PostedAt.Before(undone[j].PostedAt) })
+
+	rows, err := r.gdb.Model(&model.Transaction{}).
+		Where("account_id = ? AND state IN ?", id, []model.TransactionState{
+			model.TransactionStateCompleted,
+			model.TransactionStateReversed,
+		}).
+		Where(r.gdb.
+			Where("completed_at >= ? AND completed_at < ?", start, end).
+			Or("completed_at IS NULL AND "+postedAtSQL+" >= ? AND "+postedAtSQL+" < ?", start, end)).
+		Order(postedAtSQL + ", id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.Transaction
+		err = r.gdb.ScanRows(rows, &t)
+		if err != nil {
+			return err
+		}
+
+		e, err := statementEntry(t, common.Postings(t)[0])
+		if err != nil {
+			return err
+		}
+
+		for len(undone) > 0 && undone[0].PostedAt.Before(e.PostedAt) {
+			err = fn(undone[0])
+			if err != nil {
+				return err
+			}
+			undone = undone[1:]
+		}
+
+		err = fn(e)
+		if err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range undone {
+		err = fn(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statementEntry turns one of a transaction's postings into a statement line
+func statementEntry(t model.Transaction, p common.Posting) (statement.Entry, error) {
+	var txMoney model.TransactionMoney
+	err := json.Unmarshal(t.Money, &txMoney)
+	if err != nil {
+		return statement.Entry{}, err
+	}
+
+	memo := t.Memo
+	if p.Reversal {
+		memo = "reversal: " + memo
+	}
+
+	return statement.Entry{
+		PostedAt:      p.At,
+		TransactionID: t.ID,
+		Direction:     p.Direction,
+		Amount:        txMoney.Amount,
+		Currency:      txMoney.Currency,
+		Memo:          memo,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/partyscript/bledger/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTransactionBatch posts a batch of immediate transactions in one db transaction.
+// Items are applied in order against the running balances of their locked accounts. In
+// atomic mode the first item that fails rolls the whole batch back; in best-effort mode
+// failed items are reported and the rest are committed. An error writing to the
+// database fails the batch in either mode.
+func (tc *TransactionsController) CreateTransactionBatch(batchReq model.CreateTransactionBatchRequest, actor string) (*model.TransactionBatch, error) {
+	if len(batchReq.Items) > tc.cfg.Batch.MaxItems {
+		return nil, fmt.Errorf("a batch can't have more than %v items", tc.cfg.Batch.MaxItems)
+	}
+
+	batch := &model.TransactionBatch{
+		Mode:    batchReq.Mode,
+		Results: make([]model.TransactionBatchResult, 0, len(batchReq.Items)),
+	}
+
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	ids := make([]string, 0, len(batchReq.Items))
+	for _, item := range batchReq.Items {
+		ids = append(ids, item.AccountID)
+	}
+
+	accts, err := lockBatchAccounts(tx, ids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Only accounts an item posted to change
+	changed := map[string]*model.Account{}
+
+	for i, item := range batchReq.Items {
+		result := model.TransactionBatchResult{Index: i}
+
+		t, err := newBatchTransaction(item)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		acct, ok := accts[item.AccountID]
+		if ok {
+			err = applyTransactionEffects(t, acct, model.TransactionStateCompleted)
+		} else {
+			err = fmt.Errorf("account %v not found", item.AccountID)
+		}
+
+		if err != nil {
+			if batchReq.Mode == model.BatchModeAtomic {
+				tx.Rollback()
+				return nil, fmt.Errorf("item %v: %w", i, err)
+			}
+
+			result.Error = err.Error()
+			batch.Failed++
+			batch.Results = append(batch.Results, result)
+			continue
+		}
+
+		err = recordTransition(tx, t, model.TransactionStateCompleted, actor, "")
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		changed[acct.ID] = acct
+		result.Transaction = t
+		batch.Succeeded++
+		batch.Results = append(batch.Results, result)
+	}
+
+	err = saveAccounts(tx, changed)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// newBatchTransaction builds the unsaved transaction for one batch item
+func newBatchTransaction(item model.CreateTransactionRequest) (*model.Transaction, error) {
+	amtToStore, err := item.Money.Stored()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Transaction{
+		AccountID: item.AccountID,
+		Money:     amtToStore,
+		Direction: item.Direction,
+		Memo:      item.Memo,
+	}, nil
+}
+
+// lockBatchAccounts locks every account a batch names in ascending id order, like
+// lockAccounts, but leaves out accounts that don't exist so their items can fail alone
+func lockBatchAccounts(gtx *gorm.DB, ids []string) (map[string]*model.Account, error) {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+
+	accts := make(map[string]*model.Account, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+
+		acct := new(model.Account)
+		err := gtx.Clauses(clause.Locking{Strength: "UPDATE"}).First(acct, "id = ?", id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accts[id] = acct
+	}
+
+	return accts, nil
+}
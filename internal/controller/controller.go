@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/partyscript/bledger/internal/config"
 	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/exchange"
 
 	"github.com/partyscript/bledger/internal/cache"
 	"go.uber.org/zap"
@@ -13,6 +14,14 @@ type Manager struct {
 	Cfg          *config.GlobalConfig
 	Transactions TransactionsController
 	Accounts     AccountController
+	Transfers    TransfersController
+	Journal      JournalController
+	Conversions  ConversionsController
+	Holds        HoldsController
+	Expiry       ExpiryController
+	Verifier     VerifierController
+	Snapshots    SnapshotController
+	Export       ExportController
 }
 
 // NewControllerManager initializes a Manager
@@ -21,6 +30,7 @@ func NewControllerManager(
 	cfg *config.GlobalConfig,
 	cache *cache.Manager,
 	db *db.Manager,
+	fx exchange.FXRateProvider,
 ) Manager {
 	transactionController := NewTransactionsController(
 		logger,
@@ -36,9 +46,74 @@ func NewControllerManager(
 		db,
 	)
 
+	transferController := NewTransfersController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	journalController := NewJournalController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	conversionController := NewConversionsController(
+		logger,
+		cfg,
+		cache,
+		db,
+		fx,
+	)
+
+	holdController := NewHoldsController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	expiryController := NewExpiryController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	verifierController := NewVerifierController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	snapshotController := NewSnapshotController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
+	exportController := NewExportController(
+		logger,
+		cfg,
+		cache,
+		db,
+	)
+
 	return Manager{
 		Cfg:          cfg,
 		Transactions: transactionController,
 		Accounts:     accountController,
+		Transfers:    transferController,
+		Journal:      journalController,
+		Conversions:  conversionController,
+		Holds:        holdController,
+		Expiry:       expiryController,
+		Verifier:     verifierController,
+		Snapshots:    snapshotController,
+		Export:       exportController,
 	}
 }
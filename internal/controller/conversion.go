@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/exchange"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"go.uber.org/zap"
+)
+
+// ConversionsController is the struct that the constructor implements
+type ConversionsController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+	fx     exchange.FXRateProvider
+}
+
+// NewConversionsController initializes a ConversionsController instance
+func NewConversionsController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+	fx exchange.FXRateProvider,
+) ConversionsController {
+	return ConversionsController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+		fx:     fx,
+	}
+}
+
+// GetConversion returns a conversion by id
+func (cc *ConversionsController) GetConversion(id string) (*model.Conversion, error) {
+	var conversion model.Conversion
+
+	find := cc.db.Gorm.First(&conversion, "id = ?", id)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return &conversion, nil
+}
+
+// CreateConversion debits an account in one currency and credits the converted
+// amount to the same or another account in a different currency, at the rate
+// given by the fx provider. The converted amount is rounded down and the
+// rounding remainder, in target minor units, is recorded on the conversion.
+func (cc *ConversionsController) CreateConversion(cvReq model.CreateConversionRequest, actor string) (*model.Conversion, error) {
+	if cvReq.ToAccountID == "" {
+		cvReq.ToAccountID = cvReq.FromAccountID
+	}
+
+	if cvReq.Money.Currency == cvReq.TargetCurrency {
+		return nil, errors.New("cannot convert to the same currency")
+	}
+
+	rate, err := cc.fx.Rate(cvReq.Money.Currency, cvReq.TargetCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rates are quoted per major unit, amounts are held in minor units
+	scale, err := money.MinorUnitScale(cvReq.Money.Currency, cvReq.TargetCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, remainder, err := exchange.Convert(cvReq.Money.Amount, new(big.Rat).Mul(rate, scale))
+	if err != nil {
+		return nil, err
+	}
+
+	if converted.IsZero() {
+		return nil, errors.New("amount is too small to convert")
+	}
+
+	sourceToStore, err := cvReq.Money.Stored()
+	if err != nil {
+		return nil, err
+	}
+
+	targetToStore, err := model.TransactionMoney{
+		Amount:   converted,
+		Currency: cvReq.TargetCurrency,
+	}.Stored()
+	if err != nil {
+		return nil, err
+	}
+
+	// start transaction
+	tx := cc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// Lock both account rows in a deterministic order
+	accts, err := lockAccounts(tx, cvReq.FromAccountID, cvReq.ToAccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	conversion := &model.Conversion{
+		SourceMoney:   sourceToStore,
+		TargetMoney:   targetToStore,
+		Rate:          exchange.DecimalString(rate),
+		Remainder:     exchange.DecimalString(remainder),
+		Memo:          cvReq.Memo,
+		FromAccountID: cvReq.FromAccountID,
+		ToAccountID:   cvReq.ToAccountID,
+	}
+
+	err = tx.Create(conversion).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	debit := &model.Transaction{
+		AccountID:    cvReq.FromAccountID,
+		Money:        sourceToStore,
+		Direction:    model.TransactionDirectionDebit,
+		Memo:         cvReq.Memo,
+		Version:      0,
+		ConversionID: conversion.ID,
+	}
+
+	credit := &model.Transaction{
+		AccountID:    cvReq.ToAccountID,
+		Money:        targetToStore,
+		Direction:    model.TransactionDirectionCredit,
+		Memo:         cvReq.Memo,
+		Version:      0,
+		ConversionID: conversion.ID,
+	}
+
+	err = postLegs(tx, accts, []*model.Transaction{debit, credit}, actor)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	conversion.DebitTransactionID = debit.ID
+	conversion.CreditTransactionID = credit.ID
+
+	err = tx.Model(conversion).Updates(map[string]interface{}{
+		"debit_transaction_id":  debit.ID,
+		"credit_transaction_id": credit.ID,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return conversion, nil
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExpiryController is the struct that the constructor implements
+type ExpiryController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewExpiryController initializes an ExpiryController instance
+func NewExpiryController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) ExpiryController {
+	return ExpiryController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// Run sweeps expired pending transactions and holds on every tick until ctx is done.
+// A non-positive sweep interval disables the sweeper.
+func (ec *ExpiryController) Run(ctx context.Context) {
+	if ec.cfg.Expiry.SweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(ec.cfg.Expiry.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := ec.Sweep(time.Now())
+			if err != nil {
+				ec.logger.Errorw("expiry sweep failed", "error", err)
+			}
+			if n > 0 {
+				ec.logger.Infow("expired stale pending items", "count", n)
+			}
+		}
+	}
+}
+
+// Sweep expires pending transactions and active holds whose expiry has passed at now,
+// and returns how many it expired. Rows are claimed with SKIP LOCKED, so several
+// replicas can sweep at once without waiting on or double-expiring each other's rows.
+func (ec *ExpiryController) Sweep(now time.Time) (int, error) {
+	total := 0
+	for _, sweep := range []func(time.Time) (int, error){ec.expireTransactions, ec.expireHolds} {
+		for {
+			n, err := sweep(now)
+			total += n
+			if err != nil {
+				return total, err
+			}
+			if n < ec.cfg.Expiry.SweepBatch {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// expireTransactions expires one batch of pending transactions
+func (ec *ExpiryController) expireTransactions(now time.Time) (int, error) {
+	var txs []model.Transaction
+
+	tx := ec.db.Gorm.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("state = ? AND expires_at <= ?", model.TransactionStatePending, now).
+		Order("id").
+		Limit(ec.cfg.Expiry.SweepBatch).
+		Find(&txs).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(txs))
+	for _, t := range txs {
+		ids = append(ids, t.AccountID)
+	}
+
+	accts, err := lockAccounts(tx, ids...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for i := range txs {
+		t := &txs[i]
+
+		err = transitionTransaction(tx, t, accts[t.AccountID], model.TransactionStateExpired, common.SweeperActor, expiryReason(t.ExpiresAt))
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	err = saveAccounts(tx, accts)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(txs), tx.Commit().Error
+}
+
+// expireHolds expires one batch of active holds
+func (ec *ExpiryController) expireHolds(now time.Time) (int, error) {
+	var holds []model.Hold
+
+	tx := ec.db.Gorm.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("state = ? AND expires_at <= ?", model.HoldStateActive, now).
+		Order("id").
+		Limit(ec.cfg.Expiry.SweepBatch).
+		Find(&holds).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(holds))
+	for _, h := range holds {
+		ids = append(ids, h.AccountID)
+	}
+
+	accts, err := lockAccounts(tx, ids...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for i := range holds {
+		h := &holds[i]
+
+		var authorized model.TransactionMoney
+		err = json.Unmarshal(h.Money, &authorized)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		err = calcAccountHoldChange(authorized, accts[h.AccountID], false)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		err = tx.Model(h).Updates(map[string]interface{}{
+			"state":  model.HoldStateExpired,
+			"reason": expiryReason(h.ExpiresAt),
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	err = saveAccounts(tx, accts)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(holds), tx.Commit().Error
+}
+
+// saveAccounts writes locked account balances in ascending id order without committing
+func saveAccounts(gtx *gorm.DB, accts map[string]*model.Account) error {
+	ids := make([]string, 0, len(accts))
+	for id := range accts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		err := saveAccountBalance(gtx, accts[id])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func expiryReason(expiresAt *time.Time) string {
+	return fmt.Sprintf("expired at %v without being completed", expiresAt.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ExportController is the struct that the constructor implements
+type ExportController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewExportController initializes a ExportController instance
+func NewExportController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) ExportController {
+	return ExportController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// exportedStates are the transaction states that made it into the books, one way or another
+var exportedStates = []model.TransactionState{
+	model.TransactionStateCompleted,
+	model.TransactionStateReversed,
+	model.TransactionStateFailed,
+	model.TransactionStateVoided,
+	model.TransactionStateExpired,
+}
+
+// StreamLedger calls accountFn with every account, oldest first, then txFn with every
+// transaction that has closed, in the order it closed. Both are read from cursors so
+// the whole ledger never sits in memory.
+func (ec *ExportController) StreamLedger(accountFn func(model.Account) error, txFn func(model.Transaction) error) error {
+	err := streamRows(ec.db.Gorm, ec.db.Gorm.Model(&model.Account{}).Order("created_at, id"), accountFn)
+	if err != nil {
+		return err
+	}
+
+	return streamRows(ec.db.Gorm,
+		ec.db.Gorm.Model(&model.Transaction{}).
+			Scopes(withLinks).
+			Where("state IN ?", exportedStates).
+			Order("COALESCE(completed_at, failed_at, voided_at, expires_at, created_at), id"),
+		txFn)
+}
+
+// streamRows scans each row of query into a T and hands it to fn
+func streamRows[T any](gdb *gorm.DB, query *gorm.DB, fn func(T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		err = gdb.ScanRows(rows, &v)
+		if err != nil {
+			return err
+		}
+
+		err = fn(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
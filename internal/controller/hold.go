@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HoldsController is the struct that the constructor implements
+type HoldsController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewHoldsController initializes a HoldsController instance
+func NewHoldsController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) HoldsController {
+	return HoldsController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// GetHold returns a hold by id
+func (hc *HoldsController) GetHold(id string) (*model.Hold, error) {
+	var hold model.Hold
+
+	find := hc.db.Gorm.First(&hold, "id = ?", id)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return &hold, nil
+}
+
+// CreateHold authorizes an amount against an account by reserving it from the
+// available balance. The posted balance is untouched until the hold is captured.
+func (hc *HoldsController) CreateHold(hReq model.CreateHoldRequest) (*model.Hold, error) {
+	amtToStore, err := hReq.Money.Stored()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := common.ExpiresAt(hReq.ExpiresAt, hc.cfg.Expiry.PendingTTL, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// start transaction
+	tx := hc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// Lock the account row
+	accts, err := lockAccounts(tx, hReq.AccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	acct := accts[hReq.AccountID]
+
+	err = calcAccountHoldChange(hReq.Money, acct, true)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	hold := &model.Hold{
+		AccountID: hReq.AccountID,
+		Money:     amtToStore,
+		Memo:      hReq.Memo,
+		State:     model.HoldStateActive,
+		ExpiresAt: expiresAt,
+	}
+
+	err = tx.Create(hold).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold posts a debit for all or part of a hold's authorized amount and
+// releases the rest of the reservation
+func (hc *HoldsController) CaptureHold(id string, capReq model.CaptureHoldRequest, actor string) (*model.Hold, error) {
+	tx := hc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	hold, acct, authorized, err := lockActiveHold(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if common.IsExpired(hold.ExpiresAt, time.Now()) {
+		tx.Rollback()
+		return nil, errors.New("hold has expired")
+	}
+
+	captured := authorized
+	if capReq.Amount != nil {
+		captured.Amount = *capReq.Amount
+	}
+
+	if captured.Amount.IsZero() {
+		tx.Rollback()
+		return nil, errors.New("capture amount cannot be empty, void the hold instead")
+	}
+
+	if captured.Amount.Cmp(authorized.Amount) > 0 {
+		tx.Rollback()
+		return nil, errors.New("capture amount exceeds the authorized amount")
+	}
+
+	// Release the whole reservation, then post only what was captured
+	err = calcAccountHoldChange(authorized, acct, false)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	capturedToStore, err := captured.Stored()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	memo := capReq.Memo
+	if memo == "" {
+		memo = hold.Memo
+	}
+
+	debit := &model.Transaction{
+		AccountID: hold.AccountID,
+		Money:     capturedToStore,
+		Direction: model.TransactionDirectionDebit,
+		Memo:      memo,
+		Version:   0,
+		HoldID:    hold.ID,
+	}
+
+	err = postLegs(tx, map[string]*model.Account{acct.ID: acct}, []*model.Transaction{debit}, actor)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	hold.State = model.HoldStateCaptured
+	hold.CapturedMoney = capturedToStore
+	hold.CaptureTransactionID = debit.ID
+
+	err = tx.Model(hold).Updates(map[string]interface{}{
+		"state":                  hold.State,
+		"captured_money":         hold.CapturedMoney,
+		"capture_transaction_id": hold.CaptureTransactionID,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// VoidHold releases a hold's whole reservation without posting anything
+func (hc *HoldsController) VoidHold(id string) (*model.Hold, error) {
+	tx := hc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	hold, acct, authorized, err := lockActiveHold(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = calcAccountHoldChange(authorized, acct, false)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	hold.State = model.HoldStateVoided
+
+	err = tx.Model(hold).Update("state", hold.State).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// lockActiveHold locks a hold row and then its account row, and returns the
+// hold's authorized money. It fails unless the hold is still active.
+func lockActiveHold(gtx *gorm.DB, id string) (*model.Hold, *model.Account, model.TransactionMoney, error) {
+	hold := new(model.Hold)
+	var authorized model.TransactionMoney
+
+	err := gtx.Clauses(clause.Locking{Strength: "UPDATE"}).First(hold, "id = ?", id).Error
+	if err != nil {
+		return nil, nil, authorized, err
+	}
+
+	if hold.State != model.HoldStateActive {
+		return nil, nil, authorized, fmt.Errorf("%w: hold is %v, not ACTIVE", common.ErrIllegalTransition, hold.State)
+	}
+
+	accts, err := lockAccounts(gtx, hold.AccountID)
+	if err != nil {
+		return nil, nil, authorized, err
+	}
+
+	err = json.Unmarshal(hold.Money, &authorized)
+	if err != nil {
+		return nil, nil, authorized, err
+	}
+
+	return hold, accts[hold.AccountID], authorized, nil
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/partyscript/bledger/internal/importer"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/plaintext"
+)
+
+// ImportTransactions posts parsed CSV rows as immediate transactions, in file order, in
+// one db transaction. Every row is checked against its account's existence, currency
+// and running balance, and the import is only committed when every row passes and it
+// isn't a dry run, so a report of what would fail can be had before anything is written.
+func (tc *TransactionsController) ImportTransactions(rows []importer.Row, dryRun bool, actor string) (*importer.Report, error) {
+	report := &importer.Report{
+		DryRun: dryRun,
+		Rows:   len(rows),
+		Errors: []importer.LineError{},
+	}
+
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Err == nil {
+			ids = append(ids, row.Request.AccountID)
+		}
+	}
+
+	accts, err := lockBatchAccounts(tx, ids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	changed := map[string]*model.Account{}
+
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Fail(row.Line, row.Err)
+			continue
+		}
+
+		t, err := newBatchTransaction(row.Request)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		acct, ok := accts[row.Request.AccountID]
+		if !ok {
+			report.Fail(row.Line, fmt.Errorf("account %v not found", row.Request.AccountID))
+			continue
+		}
+
+		err = applyTransactionEffects(t, acct, model.TransactionStateCompleted)
+		if err != nil {
+			report.Fail(row.Line, err)
+			continue
+		}
+
+		err = recordTransition(tx, t, model.TransactionStateCompleted, actor, "")
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		changed[acct.ID] = acct
+		report.Valid++
+	}
+
+	if dryRun || report.Invalid > 0 {
+		tx.Rollback()
+		return report, nil
+	}
+
+	err = saveAccounts(tx, changed)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+// ImportJournal opens a parsed plain-text journal's accounts and posts its postings as
+// completed transactions dated as the journal dates them, oldest first, in one db
+// transaction. Like a CSV import, it reports every line that can't be imported,
+// including the journal's own errors, and only commits when there are none and it
+// isn't a dry run.
+func (tc *TransactionsController) ImportJournal(j *plaintext.Journal, dryRun bool, actor string) (*importer.Report, error) {
+	report := &importer.Report{
+		DryRun:   dryRun,
+		Accounts: len(j.Accounts),
+		Rows:     len(j.Postings),
+		Errors:   []importer.LineError{},
+	}
+	for _, e := range j.Errors {
+		report.Fail(e.Line, errors.New(e.Error))
+	}
+
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	names := make([]string, 0, len(j.Accounts))
+	for name := range j.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The accounts are new, so nothing else can be posting to them yet
+	accts := make(map[string]*model.Account, len(names))
+	for _, name := range names {
+		acct := *j.Accounts[name]
+		err := tx.Create(&acct).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		accts[name] = &acct
+	}
+
+	changed := map[string]*model.Account{}
+
+	for _, p := range j.Postings {
+		acct := accts[p.Account]
+		t := p.Transaction
+		t.AccountID = acct.ID
+
+		err := applyTransactionEffects(&t, acct, model.TransactionStateCompleted)
+		if err != nil {
+			report.Fail(p.Line, err)
+			continue
+		}
+
+		err = recordTransitionAt(tx, &t, model.TransactionStateCompleted, actor, "", *p.Transaction.CompletedAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		changed[acct.ID] = acct
+		report.Valid++
+	}
+
+	if dryRun || report.Invalid > 0 {
+		tx.Rollback()
+		return report, nil
+	}
+
+	err := saveAccounts(tx, changed)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+	report.Committed = true
+
+	return report, nil
+}
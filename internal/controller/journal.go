@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// JournalController is the struct that the constructor implements
+type JournalController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewJournalController initializes a JournalController instance
+func NewJournalController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) JournalController {
+	return JournalController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// GetJournalEntry returns a journal entry and its postings by id
+func (jc *JournalController) GetJournalEntry(id string) (*model.JournalEntry, error) {
+	var entry model.JournalEntry
+
+	find := jc.db.Gorm.Preload("Postings").First(&entry, "id = ?", id)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return &entry, nil
+}
+
+// CreateJournalEntry posts a balanced multi-leg journal entry. Every affected
+// account balance is updated in a single db transaction.
+func (jc *JournalController) CreateJournalEntry(jeReq model.CreateJournalEntryRequest, actor string) (*model.JournalEntry, error) {
+	err := common.ValidateBalancedPostings(jeReq.Postings)
+	if err != nil {
+		return nil, err
+	}
+
+	// start transaction
+	tx := jc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	ids := make([]string, 0, len(jeReq.Postings))
+	for _, p := range jeReq.Postings {
+		ids = append(ids, p.AccountID)
+	}
+
+	// Lock every account row in a deterministic order
+	accts, err := lockAccounts(tx, ids...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	entry := &model.JournalEntry{
+		Memo: jeReq.Memo,
+	}
+
+	err = tx.Omit(clause.Associations).Create(entry).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	legs := make([]*model.Transaction, 0, len(jeReq.Postings))
+	for _, p := range jeReq.Postings {
+		amtToStore, err := p.Money.Stored()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		memo := p.Memo
+		if memo == "" {
+			memo = jeReq.Memo
+		}
+
+		legs = append(legs, &model.Transaction{
+			AccountID:      p.AccountID,
+			Money:          amtToStore,
+			Direction:      p.Direction,
+			Memo:           memo,
+			Version:        0,
+			JournalEntryID: entry.ID,
+		})
+	}
+
+	err = postLegs(tx, accts, legs, actor)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, leg := range legs {
+		entry.Postings = append(entry.Postings, model.Posting{
+			JournalEntryID: entry.ID,
+			AccountID:      leg.AccountID,
+			Direction:      leg.Direction,
+			Money:          leg.Money,
+			Memo:           leg.Memo,
+			TransactionID:  leg.ID,
+		})
+	}
+
+	err = tx.Create(&entry.Postings).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
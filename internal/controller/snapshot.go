@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotController is the struct that the constructor implements
+type SnapshotController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewSnapshotController initializes a SnapshotController instance
+func NewSnapshotController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) SnapshotController {
+	return SnapshotController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// Run snapshots every account that is due on each tick until ctx is done.
+// A non-positive interval disables snapshots.
+func (sc *SnapshotController) Run(ctx context.Context) {
+	if sc.cfg.Snapshot.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(sc.cfg.Snapshot.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := sc.SnapshotDue(time.Now())
+			if err != nil {
+				sc.logger.Errorw("balance snapshot failed", "error", err)
+			}
+			if n > 0 {
+				sc.logger.Infow("snapshotted account balances", "count", n)
+			}
+		}
+	}
+}
+
+// SnapshotDue snapshots accounts that changed SNAPSHOT_EVERY times since their last
+// snapshot, or changed at all and haven't been snapshotted for SNAPSHOT_MAX_AGE, and
+// returns how many it snapshotted
+func (sc *SnapshotController) SnapshotDue(now time.Time) (int, error) {
+	total := 0
+	cursor := ""
+
+	for {
+		ids, err := sc.dueAccounts(cursor, now)
+		if err != nil {
+			return total, err
+		}
+
+		for _, id := range ids {
+			taken, err := sc.snapshotAccount(id, now)
+			if err != nil {
+				return total, err
+			}
+			if taken {
+				total++
+			}
+		}
+
+		if len(ids) < sc.cfg.Snapshot.Batch {
+			return total, nil
+		}
+		cursor = ids[len(ids)-1]
+	}
+}
+
+// dueAccounts returns the next page of account ids due a snapshot after cursor. An
+// account's version counts its balance changes, so the gap between it and the version
+// recorded on its last snapshot is how many changes the snapshot is behind.
+func (sc *SnapshotController) dueAccounts(cursor string, now time.Time) ([]string, error) {
+	var ids []string
+
+	every := sc.cfg.Snapshot.Every
+	maxAge := sc.cfg.Snapshot.MaxAge
+	if every == 0 && maxAge <= 0 {
+		return ids, nil
+	}
+
+	err := sc.db.Gorm.Raw(`
+		SELECT a.id FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT account_version, taken_at FROM balance_snapshots s
+			WHERE s.account_id = a.id AND s.deleted_at IS NULL
+			ORDER BY taken_at DESC LIMIT 1
+		) s ON true
+		WHERE a.deleted_at IS NULL AND a.id > ?
+		AND a.version > COALESCE(s.account_version, 1)
+		AND (
+			(? > 0 AND a.version - COALESCE(s.account_version, 1) >= ?)
+			OR (? AND COALESCE(s.taken_at, a.created_at) <= ?)
+		)
+		ORDER BY a.id LIMIT ?`,
+		cursor, every, every, maxAge > 0, now.Add(-maxAge), sc.cfg.Snapshot.Batch,
+	).Scan(&ids).Error
+
+	return ids, err
+}
+
+// snapshotAccount writes a snapshot of one account's posted balances as of now. The
+// account row is locked so no posting can commit with an earlier completion time
+// after the snapshot is taken; accounts another replica holds are skipped.
+func (sc *SnapshotController) snapshotAccount(id string, now time.Time) (bool, error) {
+	tx := sc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	acct := new(model.Account)
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ?", id).
+		Limit(1).
+		Find(acct).Error
+	if err != nil || acct.ID == "" {
+		tx.Rollback()
+		return false, err
+	}
+
+	base, err := latestSnapshot(tx, id, now)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Another replica got here first
+	if base != nil && base.AccountVersion >= acct.Version {
+		tx.Rollback()
+		return false, nil
+	}
+
+	bal, err := replayBalance(tx, acct, base, now)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	balanceJSON, err := bal.Balance.Stored()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = tx.Create(&model.BalanceSnapshot{
+		AccountID:      id,
+		TakenAt:        now,
+		AccountVersion: acct.Version,
+		Balance:        balanceJSON,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit().Error
+}
+
+// latestSnapshot returns an account's most recent snapshot taken by asOf, or nil
+func latestSnapshot(gdb *gorm.DB, accountID string, asOf time.Time) (*model.BalanceSnapshot, error) {
+	snap := new(model.BalanceSnapshot)
+
+	err := gdb.Where("account_id = ? AND taken_at <= ?", accountID, asOf).
+		Order("taken_at DESC").
+		First(snap).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+// replayBalance returns an account's balance at asOf, replaying only the transactions
+// that could have changed it since base: postings after the snapshot and anything
+// still open at asOf. Rows from before completed_at existed are always replayed.
+func replayBalance(gdb *gorm.DB, acct *model.Account, base *model.BalanceSnapshot, asOf time.Time) (*model.AccountBalanceResponse, error) {
+	var txs []model.Transaction
+
+	var bal model.AccountBalances
+	err := json.Unmarshal(acct.Balance, &bal)
+	if err != nil {
+		return nil, err
+	}
+
+	var since time.Time
+	if base != nil {
+		since = base.TakenAt
+	}
+
+	find := gdb.
+		Where("account_id = ? AND created_at <= ?", acct.ID, asOf).
+		Where(gdb.
+			Where("state = ?", model.TransactionStatePending).
+			Or("state IN ? AND (completed_at IS NULL OR completed_at > ?)", []model.TransactionState{
+				model.TransactionStateCompleted,
+				model.TransactionStateReversed,
+			}, since).
+			// Legacy REVERSED rows, undone in place, after the snapshot
+			Or("state = ? AND (reversed_at IS NULL OR reversed_at > ?)", model.TransactionStateReversed, since).
+			Or("state = ? AND failed_at > ?", model.TransactionStateFailed, asOf).
+			Or("state = ? AND voided_at > ?", model.TransactionStateVoided, asOf).
+			Or("state = ? AND expires_at > ?", model.TransactionStateExpired, asOf)).
+		Find(&txs)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	res, err := common.BalanceAsOf(bal.Currencies(), base, txs, asOf)
+	if err != nil {
+		return nil, err
+	}
+	res.AccountID = acct.ID
+
+	return res, nil
+}
@@ -3,17 +3,26 @@ package controller
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/partyscript/bledger/internal/cache"
 	"github.com/partyscript/bledger/internal/common"
 	"github.com/partyscript/bledger/internal/config"
 	"github.com/partyscript/bledger/internal/db"
 	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/segmentio/ksuid"
 	"go.uber.org/zap"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// defaultPageLimit is the page size used when a listing does not ask for one
+const defaultPageLimit = 25
+
 // TransactionsController is the struct that the constructor implements
 type TransactionsController struct {
 	logger *zap.SugaredLogger
@@ -37,87 +46,248 @@ func NewTransactionsController(
 	}
 }
 
-// CreatePendingTransaction creates a new transaction with a pending state
-func (tc *TransactionsController) CreatePendingTransaction(txReq model.CreateTransactionRequest) (*model.Transaction, error) {
-	var acct model.Account
+// CreatePendingTransaction creates a new transaction with a pending state.
+// A pending debit reserves its funds until it is executed or expires. It also returns
+// the account's version after the change, which is what the precondition is checked against.
+func (tc *TransactionsController) CreatePendingTransaction(txReq model.CreateTransactionRequest, actor string, pre common.Precondition) (*model.Transaction, uint, error) {
+	amtToStore, err := txReq.Money.Stored()
+	if err != nil {
+		return nil, 0, err
+	}
 
-	amtToStore, err := json.Marshal(txReq.Money)
+	expiresAt, err := common.ExpiresAt(txReq.ExpiresAt, tc.cfg.Expiry.PendingTTL, time.Now())
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	args := model.Transaction{
 		AccountID: txReq.AccountID,
-		Money:     datatypes.JSON(amtToStore),
+		Money:     amtToStore,
 		Direction: txReq.Direction,
 		Memo:      txReq.Memo,
-		State:     model.TransactionStatePending,
 		Version:   0,
 	}
 
-	find := tc.db.Gorm.First(&acct, &model.Account{ID: args.AccountID})
-	if find.Error != nil {
-		return nil, errors.New("account not found")
+	// start transaction
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, 0, tx.Error
+	}
+
+	// Lock the account row
+	accts, err := lockAccounts(tx, args.AccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, errors.New("account not found")
 	}
+	acct := accts[args.AccountID]
 
+	err = pre.Check(acct.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	to, reason := model.TransactionStatePending, ""
 	if !isValidCurrency(args.Money, acct.Balance) {
-		args.State = model.TransactionStateFailed
-		args.ErrorReason = "invalid currency"
-	} else {
-		args.State = model.TransactionStatePending
+		to, reason = model.TransactionStateFailed, "invalid currency"
 	}
 
-	// Calculate if we should automatically fail this
-	err = calcAccountBalanceChange(&args, &acct)
+	err = applyTransactionEffects(&args, acct, to)
 	if err != nil {
-		args.State = model.TransactionStateFailed
-		args.ErrorReason = err.Error()
+		// A transaction the account can't take fails on create
+		to, reason = model.TransactionStateFailed, err.Error()
+		err = applyTransactionEffects(&args, acct, to)
+		if err != nil {
+			tx.Rollback()
+			return nil, 0, err
+		}
+	}
+
+	if to == model.TransactionStatePending {
+		args.ExpiresAt = expiresAt
 	}
 
-	create := tc.db.Gorm.Create(&args)
-	if create.Error != nil {
-		return nil, create.Error
+	err = recordTransition(tx, &args, to, actor, reason)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
 	}
 
-	return &args, nil
+	if args.FundsHeld {
+		err = saveAccountBalance(tx, acct)
+		if err != nil {
+			tx.Rollback()
+			return nil, 0, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &args, acct.Version, nil
 }
 
 // ExecutePendingTransaction executes a pending transaction
-func (tc *TransactionsController) ExecutePendingTransaction(txID string) (*model.Transaction, error) {
+func (tc *TransactionsController) ExecutePendingTransaction(txID string, actor string, pre common.Precondition) (*model.Transaction, error) {
 	var foundTx model.Transaction
-	var acct model.Account
 
 	// start tx pipeline
 	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
 
-	// lock the tx row
-	err := tx.Set("gorm:query_option", "FOR UPDATE").First(&foundTx, &model.Transaction{ID: txID}).Error
+	// lock the tx row, so the expiry sweeper can't expire it underneath us
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&foundTx, "id = ?", txID).Error
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	err = pre.Check(foundTx.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if foundTx.State == model.TransactionStatePending && common.IsExpired(foundTx.ExpiresAt, time.Now()) {
+		tx.Rollback()
+		return nil, errors.New("transaction has expired")
+	}
+
 	// lock the account row
-	err = tx.Set("gorm:query_option", "FOR UPDATE").First(&acct, &model.Account{ID: foundTx.AccountID}).Error
+	accts, err := lockAccounts(tx, foundTx.AccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	acct := accts[foundTx.AccountID]
+
+	err = transitionTransaction(tx, &foundTx, acct, model.TransactionStateCompleted, actor, "")
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	err = executeAccountChanges(tx, &foundTx, &acct, false)
+	err = saveAccountBalance(tx, acct)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
 	return &foundTx, nil
 }
 
+// VoidPendingTransaction cancels a pending transaction and releases any funds it reserved
+func (tc *TransactionsController) VoidPendingTransaction(txID string, actor string, pre common.Precondition) (*model.Transaction, error) {
+	return tc.closePendingTransaction(txID, model.TransactionStateVoided, "", "voided", actor, pre)
+}
+
+// FailPendingTransaction fails a pending transaction with a processor's reason code
+// and releases any funds it reserved
+func (tc *TransactionsController) FailPendingTransaction(txID string, fReq model.FailTransactionRequest, actor string, pre common.Precondition) (*model.Transaction, error) {
+	reason := fReq.Reason
+	if reason == "" {
+		reason = string(fReq.ReasonCode)
+	}
+
+	return tc.closePendingTransaction(txID, model.TransactionStateFailed, fReq.ReasonCode, reason, actor, pre)
+}
+
+// closePendingTransaction moves a pending transaction to a terminal state without posting it
+func (tc *TransactionsController) closePendingTransaction(
+	txID string,
+	state model.TransactionState,
+	code model.TransactionFailureCode,
+	reason string,
+	actor string,
+	pre common.Precondition,
+) (*model.Transaction, error) {
+	transaction := new(model.Transaction)
+
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// Lock the tx row
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(transaction, "id = ?", txID).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = pre.Check(transaction.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Lock the account row
+	accts, err := lockAccounts(tx, transaction.AccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	acct := accts[transaction.AccountID]
+
+	fundsHeld := transaction.FundsHeld
+	transaction.ErrorCode = code
+
+	err = transitionTransaction(tx, transaction, acct, state, actor, reason)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Only a release changes the account
+	if fundsHeld {
+		err = saveAccountBalance(tx, acct)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// GetTransactionHistory returns every recorded state change of a transaction, oldest first
+func (tc *TransactionsController) GetTransactionHistory(txID string) ([]model.TransactionEvent, error) {
+	var transaction model.Transaction
+	events := []model.TransactionEvent{}
+
+	find := tc.db.Gorm.First(&transaction, "id = ?", txID)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	find = tc.db.Gorm.Where("transaction_id = ?", txID).Order("occurred_at ASC, sequence ASC").Find(&events)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return events, nil
+}
+
 // GetTransaction returns a transaction by id
 func (tc *TransactionsController) GetTransaction(txID string) (*model.Transaction, error) {
 	tx := new(model.Transaction)
 
-	find := tc.db.Gorm.First(&tx, "id = ?", txID)
+	find := tc.db.Gorm.Scopes(withLinks).First(&tx, "id = ?", txID)
 
 	if find.Error != nil {
 		return nil, find.Error
@@ -126,99 +296,594 @@ func (tc *TransactionsController) GetTransaction(txID string) (*model.Transactio
 	return tx, nil
 }
 
-// CreateTransaction creates a new transaction with immediate clearance
-func (tc *TransactionsController) CreateTransaction(txReq model.CreateTransactionRequest) (*model.Transaction, error) {
-	var acct model.Account
+// GetTransactionVersion returns a transaction as it stood at one of its versions
+func (tc *TransactionsController) GetTransactionVersion(txID string, version uint) (*model.Transaction, error) {
+	var tv model.TransactionVersion
 
-	amtToStore, err := json.Marshal(txReq.Money)
+	current, err := tc.GetTransaction(txID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Rows written before versioning have no snapshots but their current version
+	if version == current.Version {
+		return current, nil
+	}
+
+	find := tc.db.Gorm.First(&tv, "transaction_id = ? AND version = ?", txID, version)
+	if find.Error != nil {
+		return nil, fmt.Errorf("version %v of transaction %v not found", version, txID)
+	}
+
+	transaction := new(model.Transaction)
+	err = json.Unmarshal(tv.Snapshot, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// ListTransactionVersions returns every recorded version of a transaction, oldest first
+func (tc *TransactionsController) ListTransactionVersions(txID string) ([]model.TransactionVersion, error) {
+	var transaction model.Transaction
+	versions := []model.TransactionVersion{}
+
+	find := tc.db.Gorm.First(&transaction, "id = ?", txID)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	find = tc.db.Gorm.Where("transaction_id = ?", txID).Order("version ASC").Find(&versions)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return versions, nil
+}
+
+// ListAccountTransactions returns a page of an account's transactions in creation order.
+// Transaction ids are time-sortable ksuids, so the cursor is the last id of the previous page.
+func (tc *TransactionsController) ListAccountTransactions(accountID string, q model.ListTransactionsRequest) (*model.TransactionPage, error) {
+	var acct model.Account
+	var txs []model.Transaction
+
+	find := tc.db.Gorm.First(&acct, &model.Account{ID: accountID})
+	if find.Error != nil {
+		return nil, errors.New("account not found")
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	query := tc.db.Gorm.Where("account_id = ?", accountID)
+
+	if q.State != "" {
+		query = query.Where("state = ?", q.State)
+	}
+
+	if q.Direction != "" {
+		query = query.Where("direction = ?", q.Direction)
+	}
+
+	if q.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *q.CreatedAfter)
+	}
+
+	if q.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *q.CreatedBefore)
+	}
+
+	if q.Cursor != "" {
+		_, err := ksuid.Parse(q.Cursor)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		query = query.Where("id > ?", q.Cursor)
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	find = query.Scopes(withLinks).Order("id ASC").Limit(limit + 1).Find(&txs)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	page := &model.TransactionPage{Transactions: txs}
+	if len(txs) > limit {
+		page.Transactions = txs[:limit]
+		page.NextCursor = txs[limit-1].ID
+	}
+
+	return page, nil
+}
+
+// CreateTransaction creates a new transaction with immediate clearance. It also returns
+// the account's version after the change, which is what the precondition is checked against.
+func (tc *TransactionsController) CreateTransaction(txReq model.CreateTransactionRequest, actor string, pre common.Precondition) (*model.Transaction, uint, error) {
+	amtToStore, err := txReq.Money.Stored()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// start transaction
 	tx := tc.db.Gorm.Begin()
 
 	if tx.Error != nil {
-		return nil, tx.Error
+		return nil, 0, tx.Error
 	}
 
 	args := &model.Transaction{
 		AccountID: txReq.AccountID,
-		Money:     datatypes.JSON(amtToStore),
+		Money:     amtToStore,
 		Direction: txReq.Direction,
 		Memo:      txReq.Memo,
-		State:     model.TransactionStatePending,
 		Version:   0,
 	}
 
 	// Lock the account row
-	err = tx.Set("gorm:query_option", "FOR UPDATE").First(&acct, &model.Account{ID: args.AccountID}).Error
+	accts, err := lockAccounts(tx, args.AccountID)
 	if err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, 0, err
 	}
+	acct := accts[args.AccountID]
 
-	create := tc.db.Gorm.Create(&args)
-	if create.Error != nil {
-		return nil, create.Error
+	err = pre.Check(acct.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
 	}
 
-	err = executeAccountChanges(tx, args, &acct, false)
+	err = transitionTransaction(tx, args, acct, model.TransactionStateCompleted, actor, "")
 	if err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, 0, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return args, nil
+	return args, acct.Version, nil
 }
 
-// ReverseTransaction reverses a transaction
-func (tc *TransactionsController) ReverseTransaction(txID string) error {
-	transaction := new(model.Transaction)
-	acct := new(model.Account)
+// ReverseTransaction reverses a completed transaction by posting a new, opposite
+// transaction that references it. The original is left as it was, and its version,
+// which the precondition is checked against, is returned with the reversal.
+func (tc *TransactionsController) ReverseTransaction(txID string, actor string, pre common.Precondition) (*model.Transaction, uint, error) {
+	original := new(model.Transaction)
 
 	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, 0, tx.Error
+	}
 
-	// Lock the tx row
-	err := tx.Set("gorm:query_option", "FOR UPDATE").First(transaction, "id = ?", txID).Error
+	// Lock the tx row, so it can't be reversed twice
+	err := lockWithLinks(tx, original, txID)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, 0, err
 	}
 
-	if transaction.State != model.TransactionStateCompleted {
+	err = pre.Check(original.Version)
+	if err != nil {
 		tx.Rollback()
-		return errors.New("cannot reverse transction that did not complete")
+		return nil, 0, err
+	}
+
+	err = isReversible(original)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
 	}
 
 	// Lock the account row
-	err = tx.Set("gorm:query_option", "FOR UPDATE").First(acct, "id = ?", transaction.AccountID).Error
+	accts, err := lockAccounts(tx, original.AccountID)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, 0, err
 	}
+	acct := accts[original.AccountID]
 
-	if transaction.Direction == model.TransactionDirectionDebit {
-		transaction.Direction = model.TransactionDirectionCredit
-	} else {
-		transaction.Direction = model.TransactionDirectionDebit
+	reversal := &model.Transaction{
+		AccountID:             original.AccountID,
+		Money:                 original.Money,
+		Direction:             oppositeDirection(original.Direction),
+		Memo:                  fmt.Sprintf("reversal of %v", original.ID),
+		Version:               0,
+		ReversesTransactionID: original.ID,
+	}
+
+	err = transitionTransaction(tx, reversal, acct, model.TransactionStateCompleted, actor, fmt.Sprintf("reverses %v", original.ID))
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reversal, original.Version, nil
+}
+
+// RefundTransaction refunds part or all of a completed transaction by posting a new,
+// opposite transaction for the refunded amount. The original is left as it was, and
+// can't be refunded past its own amount by all of its refunds together. Its version,
+// which the precondition is checked against, is returned with the refund.
+func (tc *TransactionsController) RefundTransaction(txID string, rfReq model.CreateRefundRequest, actor string, pre common.Precondition) (*model.Transaction, uint, error) {
+	original := new(model.Transaction)
+
+	if rfReq.Amount.IsZero() {
+		return nil, 0, errors.New("refund amount cannot be empty")
+	}
+
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, 0, tx.Error
+	}
+
+	// Lock the tx row, so concurrent refunds see each other's totals
+	err := lockWithLinks(tx, original, txID)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = pre.Check(original.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
 	}
 
-	err = executeAccountChanges(tx, transaction, acct, true)
+	err = isRefundable(original)
 	if err != nil {
 		tx.Rollback()
+		return nil, 0, err
+	}
+
+	var origMoney model.TransactionMoney
+	err = json.Unmarshal(original.Money, &origMoney)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	// The original isn't updated, the refund row itself adds to its refunded total
+	refunded, err := original.RefundedAmount.Add(rfReq.Amount)
+	if err != nil || refunded.Cmp(origMoney.Amount) > 0 {
+		tx.Rollback()
+		remaining, _ := origMoney.Amount.Sub(original.RefundedAmount)
+		return nil, 0, fmt.Errorf("refund exceeds the %v left to refund", money.Format(remaining, origMoney.Currency))
+	}
+
+	// Lock the account row
+	accts, err := lockAccounts(tx, original.AccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+	acct := accts[original.AccountID]
+
+	amtToStore, err := model.TransactionMoney{Amount: rfReq.Amount, Currency: origMoney.Currency}.Stored()
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	memo := rfReq.Memo
+	if memo == "" {
+		memo = fmt.Sprintf("refund of %v", original.ID)
+	}
+
+	refund := &model.Transaction{
+		AccountID:             original.AccountID,
+		Money:                 amtToStore,
+		Direction:             oppositeDirection(original.Direction),
+		Memo:                  memo,
+		Version:               0,
+		RefundOfTransactionID: original.ID,
+	}
+
+	err = transitionTransaction(tx, refund, acct, model.TransactionStateCompleted, actor, fmt.Sprintf("refunds %v", original.ID))
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return refund, original.Version, nil
+}
+
+// ListRefunds returns the refunds posted against a transaction, oldest first
+func (tc *TransactionsController) ListRefunds(txID string) ([]model.Transaction, error) {
+	var original model.Transaction
+	refunds := []model.Transaction{}
+
+	find := tc.db.Gorm.First(&original, "id = ?", txID)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	find = tc.db.Gorm.Where("refund_of_transaction_id = ?", txID).Order("id ASC").Find(&refunds)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return refunds, nil
+}
+
+// isReversible checks that a transaction is an original completed posting that hasn't
+// been reversed or refunded. A partially refunded transaction is refunded the rest of
+// the way instead.
+func isReversible(transaction *model.Transaction) error {
+	err := isOriginalPosting(transaction, "reverse")
+	if err != nil {
 		return err
 	}
 
+	if !transaction.RefundedAmount.IsZero() {
+		return fmt.Errorf("%w: cannot reverse a refunded transaction, refund the rest instead", common.ErrIllegalTransition)
+	}
+
+	return nil
+}
+
+// isRefundable checks that a transaction is an original completed posting that hasn't been reversed
+func isRefundable(transaction *model.Transaction) error {
+	return isOriginalPosting(transaction, "refund")
+}
+
+func isOriginalPosting(transaction *model.Transaction, action string) error {
+	if transaction.State != model.TransactionStateCompleted {
+		return fmt.Errorf("%w: cannot %v a %v transaction", common.ErrIllegalTransition, action, transaction.State)
+	}
+
+	if transaction.ReversedBy != "" {
+		return fmt.Errorf("%w: transaction was already reversed by %v", common.ErrIllegalTransition, transaction.ReversedBy)
+	}
+
+	if transaction.ReversesTransactionID != "" {
+		return fmt.Errorf("%w: cannot %v a reversal", common.ErrIllegalTransition, action)
+	}
+
+	if transaction.RefundOfTransactionID != "" {
+		return fmt.Errorf("%w: cannot %v a refund", common.ErrIllegalTransition, action)
+	}
+
 	return nil
 }
 
+func oppositeDirection(direction model.TransactionDirection) model.TransactionDirection {
+	if direction == model.TransactionDirectionDebit {
+		return model.TransactionDirectionCredit
+	}
+	return model.TransactionDirectionDebit
+}
+
+// transitionTransaction moves a transaction to a new state through the state machine in
+// common, applying the edge's balance effects to its locked account and recording the
+// change in the transaction history. It doesn't save the account or commit.
+func transitionTransaction(
+	gtx *gorm.DB,
+	transaction *model.Transaction,
+	acct *model.Account,
+	to model.TransactionState,
+	actor string,
+	reason string,
+) error {
+	err := applyTransactionEffects(transaction, acct, to)
+	if err != nil {
+		return err
+	}
+
+	return recordTransition(gtx, transaction, to, actor, reason)
+}
+
+// priorState is the state a transaction moves from. One without an id hasn't been written yet.
+func priorState(transaction *model.Transaction) model.TransactionState {
+	if transaction.ID == "" {
+		return ""
+	}
+	return transaction.State
+}
+
+// applyTransactionEffects applies the balance effects of moving a transaction to a new
+// state to its account. The account is left untouched unless every effect succeeds.
+func applyTransactionEffects(transaction *model.Transaction, acct *model.Account, to model.TransactionState) error {
+	effects, err := common.TransactionTransition(priorState(transaction), to)
+	if err != nil {
+		return err
+	}
+
+	next := *acct
+	fundsHeld := transaction.FundsHeld
+
+	for _, effect := range effects {
+		switch effect {
+		case common.TransactionEffectReserve:
+			if transaction.Direction == model.TransactionDirectionDebit {
+				err = holdTransactionFunds(transaction, &next, true)
+				fundsHeld = err == nil
+			} else {
+				// Credits reserve nothing, but are checked so they fail early
+				probe := next
+				err = calcAccountBalanceChange(transaction, &probe)
+			}
+		case common.TransactionEffectRelease:
+			if fundsHeld {
+				err = holdTransactionFunds(transaction, &next, false)
+				fundsHeld = err != nil
+			}
+		case common.TransactionEffectPost:
+			err = calcAccountBalanceChange(transaction, &next)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	*acct = next
+	transaction.FundsHeld = fundsHeld
+
+	return nil
+}
+
+// recordTransition sets a transaction's new state, writes the transaction and appends
+// the change to its history without committing the db transaction
+func recordTransition(gtx *gorm.DB, transaction *model.Transaction, to model.TransactionState, actor string, reason string) error {
+	return recordTransitionAt(gtx, transaction, to, actor, reason, time.Now())
+}
+
+// recordTransitionAt is recordTransition for a change that happened at now, such as
+// one replayed from another ledger's history
+func recordTransitionAt(gtx *gorm.DB, transaction *model.Transaction, to model.TransactionState, actor string, reason string, now time.Time) error {
+	from := priorState(transaction)
+
+	transaction.State = to
+	switch to {
+	case model.TransactionStateCompleted:
+		transaction.CompletedAt = &now
+	case model.TransactionStateVoided:
+		transaction.VoidedAt = &now
+	case model.TransactionStateFailed:
+		// One failed on create was never pending
+		if from != "" {
+			transaction.FailedAt = &now
+		}
+	}
+
+	switch to {
+	case model.TransactionStateFailed, model.TransactionStateVoided, model.TransactionStateExpired:
+		transaction.ErrorReason = reason
+	}
+
+	err := saveTransaction(gtx, transaction, map[string]interface{}{
+		"state":        transaction.State,
+		"error_reason": transaction.ErrorReason,
+		"error_code":   transaction.ErrorCode,
+		"funds_held":   transaction.FundsHeld,
+		"completed_at": transaction.CompletedAt,
+		"failed_at":    transaction.FailedAt,
+		"voided_at":    transaction.VoidedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return gtx.Create(&model.TransactionEvent{
+		TransactionID: transaction.ID,
+		FromState:     from,
+		ToState:       to,
+		Actor:         actor,
+		Reason:        reason,
+		OccurredAt:    now,
+	}).Error
+}
+
+// transactionLinks selects a transaction with the reversal and the refunded total that
+// point back at it, since neither is stored on the transaction itself
+const transactionLinks = "transactions.*, " +
+	"(SELECT r.id FROM transactions r WHERE r.reverses_transaction_id = transactions.id AND r.deleted_at IS NULL ORDER BY r.id LIMIT 1) AS reversal_id, " +
+	"(SELECT SUM((r.money->>'amount')::numeric) FROM transactions r WHERE r.refund_of_transaction_id = transactions.id " +
+	"AND r.state = 'COMPLETED' AND r.deleted_at IS NULL) AS refunded_total"
+
+// withLinks is a query scope that fills in ReversedBy and RefundedAmount
+func withLinks(gdb *gorm.DB) *gorm.DB {
+	return gdb.Select(transactionLinks)
+}
+
+// lockWithLinks locks a transaction row, then reads it with its links. They are read
+// once the lock is held, so a reversal or refund committed while waiting for it is seen.
+func lockWithLinks(gtx *gorm.DB, transaction *model.Transaction, id string) error {
+	err := gtx.Clauses(clause.Locking{Strength: "UPDATE"}).First(transaction, "id = ?", id).Error
+	if err != nil {
+		return err
+	}
+
+	return gtx.Scopes(withLinks).First(transaction, "id = ?", id).Error
+}
+
+// saveTransaction is the only way a transaction is written. It creates a new transaction
+// as version 1, or applies changes to a locked pending one as its next version, and
+// appends an immutable snapshot of the result to transaction_versions. It doesn't commit.
+func saveTransaction(gtx *gorm.DB, transaction *model.Transaction, changes map[string]interface{}) error {
+	var err error
+	if transaction.ID == "" {
+		transaction.Version = 1
+		err = gtx.Create(transaction).Error
+	} else {
+		transaction.Version++
+		changes["version"] = transaction.Version
+
+		// Only a pending transaction changes, a posted or closed one is never updated
+		update := gtx.Model(transaction).Where("state = ?", model.TransactionStatePending).Updates(changes)
+		err = update.Error
+		if err == nil && update.RowsAffected == 0 {
+			err = fmt.Errorf("%w: transaction %v is no longer pending", common.ErrIllegalTransition, transaction.ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := transaction.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return gtx.Create(&model.TransactionVersion{
+		TransactionID: transaction.ID,
+		Version:       transaction.Version,
+		State:         transaction.State,
+		Snapshot:      snapshot,
+		RecordedAt:    time.Now(),
+	}).Error
+}
+
+// holdTransactionFunds reserves or releases a transaction's amount on its account
+func holdTransactionFunds(transaction *model.Transaction, acct *model.Account, reserve bool) error {
+	txMoney := new(model.TransactionMoney)
+	err := json.Unmarshal(transaction.Money, txMoney)
+	if err != nil {
+		return err
+	}
+
+	return calcAccountHoldChange(*txMoney, acct, reserve)
+}
+
 func calcAccountBalanceChange(tx *model.Transaction, acct *model.Account) error {
-	// Get account balance
-	acctBal := new(model.AccountMoney)
+	// Get account balances
+	var acctBals model.AccountBalances
 	txMoney := new(model.TransactionMoney)
 
-	err := json.Unmarshal(acct.Balance, acctBal)
+	err := json.Unmarshal(acct.Balance, &acctBals)
 	if err != nil {
 		return err
 	}
@@ -228,96 +893,176 @@ func calcAccountBalanceChange(tx *model.Transaction, acct *model.Account) error
 		return err
 	}
 
+	// Post to the balance held in the transaction's currency
+	acctBal := acctBals.Find(txMoney.Currency)
+	if acctBal == nil {
+		return errors.New("invalid currency")
+	}
+
 	if tx.Direction == model.TransactionDirectionDebit {
-		if txMoney.Amount > acctBal.Amount {
+		// Debits may only spend the available balance, not funds held for authorizations
+		if txMoney.Amount.Cmp(acctBal.Available()) > 0 {
+			return errors.New("insufficient funds")
+		}
+		acctBal.Amount, err = acctBal.Amount.Sub(txMoney.Amount)
+		if err != nil {
 			return errors.New("insufficient funds")
 		}
-		acctBal.Amount -= txMoney.Amount
 	} else {
-		acctBal.Amount += txMoney.Amount
+		acctBal.Amount, err = acctBal.Amount.Add(txMoney.Amount)
+		if err != nil {
+			return err
+		}
 	}
 
-	acctB, err := json.Marshal(acctBal)
+	acctB, err := acctBals.Stored()
 	if err != nil {
 		return err
 	}
 
-	acct.Balance = datatypes.JSON(acctB)
+	acct.Balance = acctB
 
 	return nil
 }
 
-func updateAccountBalance(gtx *gorm.DB, acct *model.Account) error {
-	balanceJSON, err := json.Marshal(acct.Balance)
+// calcAccountHoldChange reserves funds from, or releases funds back to, the
+// available balance of an account without touching its posted balance
+func calcAccountHoldChange(txMoney model.TransactionMoney, acct *model.Account, reserve bool) error {
+	var acctBals model.AccountBalances
+
+	err := json.Unmarshal(acct.Balance, &acctBals)
 	if err != nil {
 		return err
 	}
 
-	// Update account balance
-	err = gtx.Model(acct).Update("balance", datatypes.JSON(balanceJSON)).Error
+	acctBal := acctBals.Find(txMoney.Currency)
+	if acctBal == nil {
+		return errors.New("invalid currency")
+	}
+
+	if reserve {
+		if txMoney.Amount.Cmp(acctBal.Available()) > 0 {
+			return errors.New("insufficient funds")
+		}
+		acctBal.Held, err = acctBal.Held.Add(txMoney.Amount)
+	} else {
+		acctBal.Held, err = acctBal.Held.Sub(txMoney.Amount)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Commit the transaction
-	err = gtx.Commit().Error
+	acctB, err := acctBals.Stored()
 	if err != nil {
 		return err
 	}
 
+	acct.Balance = acctB
+
 	return nil
 }
 
-func isValidCurrency(txMoney datatypes.JSON, acctMoney datatypes.JSON) bool {
-	// Get account balance
-	balance := new(model.AccountMoney)
-	transaction := new(model.TransactionMoney)
-
-	err := json.Unmarshal(acctMoney, balance)
+// saveAccountBalance writes the account balance without committing the db transaction.
+// The balance is re-encoded so only the stored amounts are written.
+func saveAccountBalance(gtx *gorm.DB, acct *model.Account) error {
+	var bal model.AccountBalances
+	err := json.Unmarshal(acct.Balance, &bal)
 	if err != nil {
-		return false
+		return err
 	}
 
-	err = json.Unmarshal(txMoney, transaction)
+	balanceJSON, err := bal.Stored()
 	if err != nil {
-		return false
+		return err
 	}
 
-	if transaction.Currency != balance.Currency {
-		return false
+	// Update account balance as the account's next version
+	acct.Version++
+	return gtx.Model(acct).Updates(map[string]interface{}{
+		"balance": balanceJSON,
+		"version": acct.Version,
+	}).Error
+}
+
+// postLegs applies completed legs to their locked accounts, then writes the legs and
+// the new account balances without committing the db transaction. Credits are applied
+// before debits so an account on both sides of a posting is checked on its net position.
+func postLegs(gtx *gorm.DB, accts map[string]*model.Account, legs []*model.Transaction, actor string) error {
+	ordered := make([]*model.Transaction, 0, len(legs))
+	for _, dir := range []model.TransactionDirection{model.TransactionDirectionCredit, model.TransactionDirectionDebit} {
+		for _, leg := range legs {
+			if leg.Direction == dir {
+				ordered = append(ordered, leg)
+			}
+		}
+	}
+	if len(ordered) != len(legs) {
+		return errors.New("invalid direction")
 	}
 
-	return true
-}
-func executeAccountChanges(gtx *gorm.DB, transaction *model.Transaction, account *model.Account, reversing bool) error {
-	if reversing {
-		transaction.State = model.TransactionStateReversed
-	} else {
-		if !common.IsCompletableState(*transaction) {
-			gtx.Rollback()
-			return errors.New("transaction is not in a completable state")
+	for _, leg := range ordered {
+		acct, ok := accts[leg.AccountID]
+		if !ok {
+			return fmt.Errorf("account %v not found", leg.AccountID)
+		}
+
+		if !isValidCurrency(leg.Money, acct.Balance) {
+			return errors.New("invalid currency")
+		}
+
+		err := applyTransactionEffects(leg, acct, model.TransactionStateCompleted)
+		if err != nil {
+			return fmt.Errorf("account %v: %w", acct.ID, err)
 		}
-		transaction.State = model.TransactionStateCompleted
 	}
 
-	// Update account balance
-	err := calcAccountBalanceChange(transaction, account)
-	if err != nil {
-		gtx.Rollback()
-		return err
+	for _, leg := range legs {
+		err := recordTransition(gtx, leg, model.TransactionStateCompleted, actor, "")
+		if err != nil {
+			return err
+		}
 	}
 
-	// Save transaction status
-	err = gtx.Model(*transaction).Update("state", transaction.State).Error
+	return saveAccounts(gtx, accts)
+}
+
+// lockAccounts locks the account rows in ascending id order so that concurrent
+// postings touching the same accounts always acquire their locks in the same order
+func lockAccounts(gtx *gorm.DB, ids ...string) (map[string]*model.Account, error) {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+
+	accts := make(map[string]*model.Account, len(sorted))
+	for _, id := range sorted {
+		if _, ok := accts[id]; ok {
+			continue
+		}
+
+		acct := new(model.Account)
+		err := gtx.Clauses(clause.Locking{Strength: "UPDATE"}).First(acct, "id = ?", id).Error
+		if err != nil {
+			return nil, fmt.Errorf("account %v not found", id)
+		}
+		accts[id] = acct
+	}
+
+	return accts, nil
+}
+
+func isValidCurrency(txMoney datatypes.JSON, acctMoney datatypes.JSON) bool {
+	// Get account balances
+	var balances model.AccountBalances
+	transaction := new(model.TransactionMoney)
+
+	err := json.Unmarshal(acctMoney, &balances)
 	if err != nil {
-		return err
+		return false
 	}
 
-	err = updateAccountBalance(gtx, account)
+	err = json.Unmarshal(txMoney, transaction)
 	if err != nil {
-		gtx.Rollback()
-		return err
+		return false
 	}
 
-	return nil
+	return balances.Find(transaction.Currency) != nil
 }
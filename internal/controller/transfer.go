@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+)
+
+// TransfersController is the struct that the constructor implements
+type TransfersController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewTransfersController initializes a TransfersController instance
+func NewTransfersController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) TransfersController {
+	return TransfersController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// GetTransfer returns a transfer by id
+func (tc *TransfersController) GetTransfer(id string) (*model.Transfer, error) {
+	var transfer model.Transfer
+
+	find := tc.db.Gorm.First(&transfer, "id = ?", id)
+	if find.Error != nil {
+		return nil, find.Error
+	}
+
+	return &transfer, nil
+}
+
+// CreateTransfer debits one account and credits another in a single db transaction.
+// Either both legs are posted or neither is.
+func (tc *TransfersController) CreateTransfer(trReq model.CreateTransferRequest, actor string) (*model.Transfer, error) {
+	if trReq.FromAccountID == trReq.ToAccountID {
+		return nil, errors.New("cannot transfer to the same account")
+	}
+
+	amtToStore, err := trReq.Money.Stored()
+	if err != nil {
+		return nil, err
+	}
+
+	// start transaction
+	tx := tc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// Lock both account rows in a deterministic order
+	accts, err := lockAccounts(tx, trReq.FromAccountID, trReq.ToAccountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	from := accts[trReq.FromAccountID]
+	to := accts[trReq.ToAccountID]
+
+	transfer := &model.Transfer{
+		Money:         amtToStore,
+		Memo:          trReq.Memo,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+	}
+
+	err = tx.Create(transfer).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	debit := &model.Transaction{
+		AccountID:  from.ID,
+		Money:      amtToStore,
+		Direction:  model.TransactionDirectionDebit,
+		Memo:       trReq.Memo,
+		Version:    0,
+		TransferID: transfer.ID,
+	}
+
+	credit := &model.Transaction{
+		AccountID:  to.ID,
+		Money:      amtToStore,
+		Direction:  model.TransactionDirectionCredit,
+		Memo:       trReq.Memo,
+		Version:    0,
+		TransferID: transfer.ID,
+	}
+
+	err = postLegs(tx, accts, []*model.Transaction{debit, credit}, actor)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	transfer.DebitTransactionID = debit.ID
+	transfer.CreditTransactionID = credit.ID
+
+	err = tx.Model(transfer).Updates(map[string]interface{}{
+		"debit_transaction_id":  debit.ID,
+		"credit_transaction_id": credit.ID,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/partyscript/bledger/internal/cache"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/config"
+	"github.com/partyscript/bledger/internal/db"
+	"github.com/partyscript/bledger/internal/model"
+	"go.uber.org/zap"
+)
+
+// verifyBatchSize is how many account ids the verifier reads per page
+const verifyBatchSize = 100
+
+// VerifierController is the struct that the constructor implements
+type VerifierController struct {
+	logger *zap.SugaredLogger
+	cfg    *config.GlobalConfig
+	cache  *cache.Manager
+	db     *db.Manager
+}
+
+// NewVerifierController initializes a VerifierController instance
+func NewVerifierController(
+	logger *zap.SugaredLogger,
+	cfg *config.GlobalConfig,
+	cache *cache.Manager,
+	db *db.Manager,
+) VerifierController {
+	return VerifierController{
+		logger: logger,
+		cfg:    cfg,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// Verify replays every account's transactions and holds and reports each stored
+// balance that drifted from its history. With repair, drifted balances are rewritten
+// to the replayed amounts.
+func (vc *VerifierController) Verify(repair bool) (*model.VerificationReport, error) {
+	report := &model.VerificationReport{
+		StartedAt: time.Now(),
+		Repair:    repair,
+		Drifts:    []model.BalanceDrift{},
+	}
+
+	cursor := ""
+	for {
+		var ids []string
+
+		find := vc.db.Gorm.Model(&model.Account{}).
+			Where("id > ?", cursor).
+			Order("id ASC").
+			Limit(verifyBatchSize).
+			Pluck("id", &ids)
+		if find.Error != nil {
+			return nil, find.Error
+		}
+
+		for _, id := range ids {
+			drifts, err := vc.verifyAccount(id, repair)
+			if err != nil {
+				return nil, err
+			}
+
+			report.AccountsChecked++
+			if len(drifts) > 0 {
+				report.AccountsDrifted++
+				if drifts[0].Repaired {
+					report.AccountsRepaired++
+				}
+				report.Drifts = append(report.Drifts, drifts...)
+			}
+		}
+
+		if len(ids) < verifyBatchSize {
+			break
+		}
+		cursor = ids[len(ids)-1]
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// verifyAccount checks one account under its row lock, so postings can't land between
+// reading the stored balance and replaying the history
+func (vc *VerifierController) verifyAccount(id string, repair bool) ([]model.BalanceDrift, error) {
+	var txs []model.Transaction
+	var holds []model.Hold
+	var stored model.AccountBalances
+
+	tx := vc.db.Gorm.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	accts, err := lockAccounts(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	acct := accts[id]
+
+	err = json.Unmarshal(acct.Balance, &stored)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Where("account_id = ?", id).Find(&txs).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Where("account_id = ? AND state = ?", id, model.HoldStateActive).Find(&holds).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	expected, shortfalls, err := common.ReplayBalances(stored.Currencies(), txs, holds)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	drifts := common.DiffBalances(id, stored, expected, shortfalls)
+	if len(drifts) == 0 || !repair {
+		tx.Rollback()
+		return drifts, nil
+	}
+
+	// An overdrawn history has no balance to repair to, so the account is left for
+	// someone to look at rather than rewritten
+	if len(shortfalls) > 0 {
+		tx.Rollback()
+		vc.logger.Errorw("account history is overdrawn, not repairing", "account_id", id, "currencies", len(shortfalls))
+		return drifts, nil
+	}
+
+	acct.Balance, err = expected.Stored()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = saveAccountBalance(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range drifts {
+		drifts[i].Repaired = true
+	}
+	vc.logger.Warnw("repaired drifted account balance", "account_id", id, "drifts", len(drifts))
+
+	return drifts, nil
+}
@@ -26,6 +26,14 @@ func migrate(db *gorm.DB) error {
 	err := db.AutoMigrate(
 		&model.Transaction{},
 		&model.Account{},
+		&model.Transfer{},
+		&model.JournalEntry{},
+		&model.Posting{},
+		&model.Conversion{},
+		&model.Hold{},
+		&model.TransactionEvent{},
+		&model.TransactionVersion{},
+		&model.BalanceSnapshot{},
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,103 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// maxDecimalPlaces bounds how far DecimalString looks for a terminating expansion
+const maxDecimalPlaces = 30
+
+// FXRateProvider is an interface for a source of foreign exchange rates.
+// Rate returns how many units of `to` one unit of `from` buys.
+type FXRateProvider interface {
+	Rate(from string, to string) (*big.Rat, error)
+}
+
+// PairKey returns the lookup key for a currency pair, e.g. USD/EUR
+func PairKey(from string, to string) string {
+	return fmt.Sprintf("%v/%v", strings.ToUpper(from), strings.ToUpper(to))
+}
+
+// ParseRates parses a table of decimal rates keyed by currency pair
+func ParseRates(table map[string]string) (map[string]*big.Rat, error) {
+	rates := make(map[string]*big.Rat, len(table))
+	for pair, value := range table {
+		parts := strings.Split(pair, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid currency pair %v", pair)
+		}
+
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %v for %v", value, pair)
+		}
+
+		rates[PairKey(parts[0], parts[1])] = rate
+	}
+	return rates, nil
+}
+
+// lookup finds a rate in a table, inverting the opposite pair when only it is present
+func lookup(rates map[string]*big.Rat, from string, to string) (*big.Rat, error) {
+	if rate, ok := rates[PairKey(from, to)]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+
+	if rate, ok := rates[PairKey(to, from)]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+
+	return nil, fmt.Errorf("no exchange rate for %v", PairKey(from, to))
+}
+
+// Convert applies a rate to an amount. It returns the converted amount rounded
+// down to a whole unit and the fraction of a unit lost to rounding.
+func Convert(amount money.Amount, rate *big.Rat) (money.Amount, *big.Rat, error) {
+	if rate == nil || rate.Sign() <= 0 {
+		return money.Amount{}, nil, errors.New("exchange rate must be positive")
+	}
+
+	product := new(big.Rat).Mul(new(big.Rat).SetInt(amount.BigInt()), rate)
+	whole := new(big.Int).Quo(product.Num(), product.Denom())
+
+	converted, err := money.ParseAmount(whole.String())
+	if err != nil {
+		return money.Amount{}, nil, err
+	}
+
+	remainder := new(big.Rat).Sub(product, new(big.Rat).SetInt(whole))
+
+	return converted, remainder, nil
+}
+
+// DecimalString renders a rational as an exact decimal when it has a terminating
+// expansion, and as a fraction otherwise
+func DecimalString(r *big.Rat) string {
+	ten := big.NewInt(10)
+	scale := big.NewInt(1)
+	for places := 0; places <= maxDecimalPlaces; places++ {
+		if new(big.Int).Mod(scale, r.Denom()).Sign() == 0 {
+			return r.FloatString(places)
+		}
+		scale.Mul(scale, ten)
+	}
+	return r.String()
+}
+
+// NewProvider returns the FXRateProvider named by kind, either "static" over an
+// in-memory rate table or "file" over a JSON rate file
+func NewProvider(kind string, table map[string]string, path string) (FXRateProvider, error) {
+	switch kind {
+	case "", "static":
+		return NewStaticProvider(table)
+	case "file":
+		return NewFileProvider(path)
+	default:
+		return nil, fmt.Errorf("unknown fx provider %v", kind)
+	}
+}
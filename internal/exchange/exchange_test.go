@@ -0,0 +1,83 @@
+package exchange
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticProvider(t *testing.T) {
+	p, err := NewStaticProvider(map[string]string{"USD/EUR": "0.92", "gbp/usd": "1.25"})
+	assert.NoError(t, err)
+
+	rate, err := p.Rate("USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.92", DecimalString(rate))
+
+	// Inverse of a configured pair
+	rate, err = p.Rate("USD", "GBP")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8", DecimalString(rate))
+
+	_, err = p.Rate("USD", "JPY")
+	assert.Error(t, err)
+}
+
+func TestStaticProviderRejectsBadRates(t *testing.T) {
+	_, err := NewStaticProvider(map[string]string{"USD/EUR": "-1"})
+	assert.Error(t, err)
+
+	_, err = NewStaticProvider(map[string]string{"USDEUR": "1"})
+	assert.Error(t, err)
+
+	_, err = NewStaticProvider(map[string]string{"USD/EUR": "abc"})
+	assert.Error(t, err)
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"EUR/USD": "1.0875"}`), 0600)
+	assert.NoError(t, err)
+
+	p, err := NewFileProvider(path)
+	assert.NoError(t, err)
+
+	rate, err := p.Rate("EUR", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0875", DecimalString(rate))
+
+	_, err = NewFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		amount    uint64
+		rate      string
+		expected  string
+		remainder string
+	}{
+		{amount: 1000, rate: "0.92", expected: "920", remainder: "0"},
+		{amount: 1001, rate: "0.92", expected: "920", remainder: "0.92"},
+		{amount: 333, rate: "1.0875", expected: "362", remainder: "0.1375"},
+		{amount: 10, rate: "1/3", expected: "3", remainder: "1/3"},
+		{amount: 18446744073709551615, rate: "1000", expected: "18446744073709551615000", remainder: "0"},
+	}
+
+	for _, tc := range testCases {
+		rate, ok := new(big.Rat).SetString(tc.rate)
+		assert.True(t, ok)
+
+		converted, remainder, err := Convert(money.NewAmount(tc.amount), rate)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, converted.String())
+		assert.Equal(t, tc.remainder, DecimalString(remainder))
+	}
+
+	_, _, err := Convert(money.NewAmount(10), big.NewRat(0, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package exchange
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileProvider is an FXRateProvider backed by a JSON file of decimal rates keyed
+// by currency pair. The file is read again whenever its modification time changes.
+type FileProvider struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	rates   map[string]*big.Rat
+}
+
+// NewFileProvider returns a new instance of FileProvider and loads the rate file
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{
+		path: path,
+	}
+
+	err := p.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Rate returns the rate for a currency pair
+func (p *FileProvider) Rate(from string, to string) (*big.Rat, error) {
+	err := p.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return lookup(p.rates, from, to)
+}
+
+func (p *FileProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.rates != nil && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var table map[string]string
+	err = json.Unmarshal(b, &table)
+	if err != nil {
+		return err
+	}
+
+	rates, err := ParseRates(table)
+	if err != nil {
+		return err
+	}
+
+	p.rates = rates
+	p.modTime = info.ModTime()
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package exchange
+
+import (
+	"math/big"
+)
+
+// StaticProvider is an FXRateProvider backed by a fixed in-memory rate table
+type StaticProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticProvider returns a new instance of StaticProvider from a table of
+// decimal rates keyed by currency pair, e.g. {"USD/EUR": "0.92"}
+func NewStaticProvider(table map[string]string) (*StaticProvider, error) {
+	rates, err := ParseRates(table)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticProvider{
+		rates: rates,
+	}, nil
+}
+
+// Rate returns the rate for a currency pair
+func (p *StaticProvider) Rate(from string, to string) (*big.Rat, error) {
+	return lookup(p.rates, from, to)
+}
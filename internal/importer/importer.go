@@ -0,0 +1,236 @@
+// Package importer reads transactions from CSV files and reports, line by line, the
+// rows that can't be imported
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// Fields a CSV column can be mapped to. Memo is optional; rows without one get a memo
+// naming the line they were imported from.
+const (
+	FieldAccountID = "account_id"
+	FieldDirection = "direction"
+	FieldAmount    = "amount"
+	FieldCurrency  = "currency"
+	FieldMemo      = "memo"
+)
+
+var requiredFields = []string{FieldAccountID, FieldDirection, FieldAmount, FieldCurrency}
+
+// Columns maps each field to the header of the CSV column it is read from
+type Columns map[string]string
+
+// DefaultColumns reads every field from the column named after it
+func DefaultColumns() Columns {
+	return Columns{
+		FieldAccountID: FieldAccountID,
+		FieldDirection: FieldDirection,
+		FieldAmount:    FieldAmount,
+		FieldCurrency:  FieldCurrency,
+		FieldMemo:      FieldMemo,
+	}
+}
+
+// ParseColumns overrides the default columns with a spec like
+// "amount=Amount (cents),account_id=Account"
+func ParseColumns(spec string) (Columns, error) {
+	cols := DefaultColumns()
+	if spec == "" {
+		return cols, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=header", pair)
+		}
+		if _, known := cols[field]; !known {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		cols[field] = strings.TrimSpace(header)
+	}
+
+	return cols, nil
+}
+
+// Row is one parsed CSV line. Err is set when the line itself is invalid, and
+// Request is only meaningful when it isn't.
+type Row struct {
+	Line    int
+	Request model.CreateTransactionRequest
+	Err     error
+}
+
+// Parse reads every row of a CSV file with a header line. It fails outright when the
+// header is missing a mapped column or the file has more than maxRows rows; problems
+// with single rows are returned on the row.
+func Parse(r io.Reader, cols Columns, maxRows int) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		// Spreadsheets often save a byte order mark ahead of the first header
+		index[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+
+	pos := map[string]int{}
+	for _, field := range requiredFields {
+		i, ok := index[cols[field]]
+		if !ok {
+			return nil, fmt.Errorf("csv header has no %q column for %v", cols[field], field)
+		}
+		pos[field] = i
+	}
+	if i, ok := index[cols[FieldMemo]]; ok {
+		pos[FieldMemo] = i
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("csv file has more than %v rows", maxRows)
+		}
+
+		// A record that failed to parse has no field positions; its line comes
+		// from the error instead
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, parseRow(line, record, pos))
+	}
+}
+
+// parseRow maps one record to a transaction request and checks its fields
+func parseRow(line int, record []string, pos map[string]int) Row {
+	row := Row{Line: line}
+
+	field := func(name string) string {
+		i, ok := pos[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var problems []string
+
+	row.Request.AccountID = field(FieldAccountID)
+	if row.Request.AccountID == "" {
+		problems = append(problems, "account_id is empty")
+	}
+
+	row.Request.Direction = model.TransactionDirection(strings.ToUpper(field(FieldDirection)))
+	if row.Request.Direction != model.TransactionDirectionCredit && row.Request.Direction != model.TransactionDirectionDebit {
+		problems = append(problems, fmt.Sprintf("direction %q must be CREDIT or DEBIT", field(FieldDirection)))
+	}
+
+	amount, err := money.ParseAmount(field(FieldAmount))
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if amount.IsZero() {
+		problems = append(problems, "amount must be greater than zero")
+	}
+	row.Request.Money.Amount = amount
+
+	row.Request.Money.Currency = strings.ToUpper(field(FieldCurrency))
+	err = money.ValidateCurrency(row.Request.Money.Currency)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	row.Request.Memo = field(FieldMemo)
+	if row.Request.Memo == "" {
+		row.Request.Memo = fmt.Sprintf("imported from csv line %v", line)
+	}
+
+	if len(problems) > 0 {
+		row.Err = errors.New(strings.Join(problems, "; "))
+	}
+
+	return row
+}
+
+// LineError is a row that can't be imported and why
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report is the outcome of an import. Nothing is committed unless every row is valid
+// and the import isn't a dry run. Accounts counts the accounts a journal import opens.
+type Report struct {
+	DryRun    bool        `json:"dry_run"`
+	Committed bool        `json:"committed"`
+	Accounts  int         `json:"accounts,omitempty"`
+	Rows      int         `json:"rows"`
+	Valid     int         `json:"valid"`
+	Invalid   int         `json:"invalid"`
+	Errors    []LineError `json:"errors"`
+}
+
+// Fail records that the row on line couldn't be imported
+func (r *Report) Fail(line int, err error) {
+	r.Invalid++
+	r.Errors = append(r.Errors, LineError{Line: line, Error: err.Error()})
+}
+
+// WriteText writes the report for a terminal, one line per error in line order
+func (r *Report) WriteText(w io.Writer) error {
+	errs := append([]LineError{}, r.Errors...)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+
+	for _, e := range errs {
+		_, err := fmt.Fprintf(w, "line %v: %v\n", e.Line, e.Error)
+		if err != nil {
+			return err
+		}
+	}
+
+	outcome := "nothing committed"
+	switch {
+	case r.Committed:
+		outcome = "committed"
+	case r.DryRun:
+		outcome = "dry run, nothing committed"
+	}
+
+	accounts := ""
+	if r.Accounts > 0 {
+		accounts = fmt.Sprintf("%v accounts, ", r.Accounts)
+	}
+
+	_, err := fmt.Fprintf(w, "%v%v rows, %v valid, %v invalid: %v\n", accounts, r.Rows, r.Valid, r.Invalid, outcome)
+	return err
+}
@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	file := "\ufeffaccount_id,direction,amount,currency,memo\n" +
+		"acct_1,credit,1000,usd,opening balance\n" +
+		"\n" +
+		"acct_2,DEBIT,250,EUR,\n" +
+		"acct_3,SIDEWAYS,12.50,XXX,bad\n" +
+		",CREDIT,1,USD,no account\n"
+
+	rows, err := Parse(strings.NewReader(file), DefaultColumns(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "acct_1", rows[0].Request.AccountID)
+	assert.Equal(t, model.TransactionDirectionCredit, rows[0].Request.Direction)
+	assert.Equal(t, "1000", rows[0].Request.Money.Amount.String())
+	assert.Equal(t, "USD", rows[0].Request.Money.Currency)
+	assert.Equal(t, "opening balance", rows[0].Request.Memo)
+
+	// Blank lines are skipped but still counted
+	assert.NoError(t, rows[1].Err)
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Equal(t, "imported from csv line 4", rows[1].Request.Memo)
+
+	assert.Error(t, rows[2].Err)
+	assert.Contains(t, rows[2].Err.Error(), `direction "SIDEWAYS"`)
+	assert.Contains(t, rows[2].Err.Error(), "minor units")
+	assert.Contains(t, rows[2].Err.Error(), "unknown currency XXX")
+
+	assert.EqualError(t, rows[3].Err, "account_id is empty")
+}
+
+func TestParseZeroAmount(t *testing.T) {
+	file := "account_id,direction,amount,currency\nacct_1,CREDIT,0,USD\nacct_1,CREDIT,,USD\n"
+
+	rows, err := Parse(strings.NewReader(file), DefaultColumns(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.EqualError(t, rows[0].Err, "amount must be greater than zero")
+	assert.Error(t, rows[1].Err)
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := ParseColumns("amount=Amount (cents), account_id=Account")
+	assert.NoError(t, err)
+	assert.Equal(t, "Amount (cents)", cols[FieldAmount])
+	assert.Equal(t, "Account", cols[FieldAccountID])
+	assert.Equal(t, "currency", cols[FieldCurrency])
+
+	file := "Account,direction,Amount (cents),currency\nacct_1,CREDIT,5,USD\n"
+	rows, err := Parse(strings.NewReader(file), cols, 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, "acct_1", rows[0].Request.AccountID)
+
+	_, err = ParseColumns("colour=Colour")
+	assert.Error(t, err)
+	_, err = ParseColumns("amount")
+	assert.Error(t, err)
+}
+
+func TestParseFileErrors(t *testing.T) {
+	_, err := Parse(strings.NewReader(""), DefaultColumns(), 10)
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader("account_id,direction,amount\n"), DefaultColumns(), 10)
+	assert.EqualError(t, err, `csv header has no "currency" column for currency`)
+
+	file := "account_id,direction,amount,currency\na,CREDIT,1,USD\nb,CREDIT,1,USD\n"
+	_, err = Parse(strings.NewReader(file), DefaultColumns(), 1)
+	assert.EqualError(t, err, "csv file has more than 1 rows")
+
+	// A malformed quote fails only its own row
+	file = "account_id,direction,amount,currency\na,CREDIT,1,\"USD\nb,CREDIT,1,USD\n"
+	rows, err := Parse(strings.NewReader(file), DefaultColumns(), 10)
+	assert.NoError(t, err)
+	assert.Error(t, rows[0].Err)
+
+	// A row that fails to parse reports the line it started on
+	cols, err := ParseColumns("account_id=h,direction=h,amount=h,currency=h")
+	assert.NoError(t, err)
+
+	rows, err = Parse(strings.NewReader("h\na\"b,c\n"), cols, 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Error(t, rows[0].Err)
+
+	rows, err = Parse(strings.NewReader("h\n\"abc\n"), cols, 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Error(t, rows[0].Err)
+}
+
+func TestReportWriteText(t *testing.T) {
+	r := &Report{DryRun: true, Rows: 3, Valid: 1}
+	r.Fail(4, errors.New("insufficient funds"))
+	r.Fail(2, errors.New("account acct_9 not found"))
+
+	var out bytes.Buffer
+	assert.NoError(t, r.WriteText(&out))
+	assert.Equal(t, "line 2: account acct_9 not found\n"+
+		"line 4: insufficient funds\n"+
+		"3 rows, 1 valid, 2 invalid: dry run, nothing committed\n", out.String())
+
+	r = &Report{Committed: true, Accounts: 2, Rows: 5, Valid: 5}
+	out.Reset()
+	assert.NoError(t, r.WriteText(&out))
+	assert.Equal(t, "2 accounts, 5 rows, 5 valid, 0 invalid: committed\n", out.String())
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// AdminAuth is a middleware that only lets through requests bearing the admin token.
+// Every request is refused when no token is configured.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(
+				common.WrapAPIError("admin endpoints are disabled",
+					common.BLedgerForbiddenError,
+					pkg.APIVersion,
+				))
+			return
+		}
+
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(
+				common.WrapAPIError("admin token is invalid",
+					common.BLedgerUnauthorizedError,
+					pkg.APIVersion,
+				))
+			return
+		}
+
+		c.Next()
+	}
+}
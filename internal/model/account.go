@@ -1,6 +1,10 @@
 package model
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
 	"github.com/segmentio/ksuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -13,18 +17,126 @@ type Account struct {
 	Balance     datatypes.JSON `json:"balance"`
 	Name        string         `binding:"required" json:"name"`
 	Description string         `binding:"required" json:"description"`
+	Version     uint           `json:"version"`
 }
 
-// AccountMoney is the model for an account balance in a currnecy
+// AccountMoney is the model for an account balance in a currnecy.
+// Amount is the posted balance and Held is the part of it reserved by open
+// authorizations, both in the currency's ISO 4217 minor unit, e.g. cents for USD.
 type AccountMoney struct {
-	Amount   uint64 `binding:"required" gorm:"-" json:"amount"`
-	Currency string `binding:"required" gorm:"-" json:"currency"`
+	Amount   money.Amount `gorm:"-" json:"amount"`
+	Held     money.Amount `gorm:"-" json:"held"`
+	Currency string       `binding:"required" gorm:"-" json:"currency"`
+}
+
+// AccountBalances is the model for an account's balances, one per currency held
+type AccountBalances []AccountMoney
+
+// AccountBalanceResponse is the model for an account balance at a point in time
+type AccountBalanceResponse struct {
+	AccountID      string          `json:"account_id"`
+	AsOf           time.Time       `json:"as_of"`
+	Balance        AccountBalances `json:"balance"`
+	PendingCredits AccountBalances `json:"pending_credits"`
+	PendingDebits  AccountBalances `json:"pending_debits"`
 }
 
-// CreateAccountRequest is the model for an account create request
+// CreateAccountRequest is the model for an account create request.
+// Currency opens a single-currency account, Currencies opens one balance per currency.
 type CreateAccountRequest struct {
-	Name        string `binding:"required" json:"name"`
-	Description string `binding:"required" json:"description"`
+	Name        string   `binding:"required" json:"name"`
+	Description string   `binding:"required" json:"description"`
+	Currency    string   `binding:"omitempty,currency" json:"currency,omitempty"`
+	Currencies  []string `binding:"omitempty,dive,currency" json:"currencies,omitempty"`
+}
+
+// Available returns the posted balance less the amount held for authorizations
+func (m AccountMoney) Available() money.Amount {
+	available, err := m.Amount.Sub(m.Held)
+	if err != nil {
+		return money.Amount{}
+	}
+	return available
+}
+
+// MarshalJSON encodes the balance for a response, alongside its posted and
+// available amounts and its posted amount formatted in major units
+func (m AccountMoney) MarshalJSON() ([]byte, error) {
+	type plain AccountMoney
+	return json.Marshal(struct {
+		plain
+		Posted    money.Amount `json:"posted"`
+		Available money.Amount `json:"available"`
+		Formatted string       `json:"formatted,omitempty"`
+	}{plain(m), m.Amount, m.Available(), money.Format(m.Amount, m.Currency)})
+}
+
+// Stored encodes the balances as they are stored, without the amounts derived
+// from them for responses
+func (b AccountBalances) Stored() (datatypes.JSON, error) {
+	type stored AccountMoney
+	list := make([]stored, 0, len(b))
+	for _, m := range b {
+		list = append(list, stored(m))
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// balanceView re-encodes stored balances for a response
+func balanceView(stored datatypes.JSON) (json.RawMessage, error) {
+	if len(stored) == 0 {
+		return nil, nil
+	}
+
+	var bal AccountBalances
+	err := json.Unmarshal(stored, &bal)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bal)
+}
+
+// Find returns the balance held in a currency, or nil if the account does not hold it
+func (b AccountBalances) Find(currency string) *AccountMoney {
+	for i := range b {
+		if b[i].Currency == currency {
+			return &b[i]
+		}
+	}
+	return nil
+}
+
+// Currencies returns the currencies held, in balance order
+func (b AccountBalances) Currencies() []string {
+	currencies := make([]string, 0, len(b))
+	for _, m := range b {
+		currencies = append(currencies, m.Currency)
+	}
+	return currencies
+}
+
+// UnmarshalJSON decodes balances, accepting the single balance object
+// stored by accounts created before multi-currency support
+func (b *AccountBalances) UnmarshalJSON(data []byte) error {
+	var list []AccountMoney
+	err := json.Unmarshal(data, &list)
+	if err == nil {
+		*b = list
+		return nil
+	}
+
+	var single AccountMoney
+	err = json.Unmarshal(data, &single)
+	if err != nil {
+		return err
+	}
+	*b = AccountBalances{single}
+	return nil
 }
 
 // BeforeCreate is a method hook that generates a custom sorted id
@@ -33,6 +145,39 @@ func (a *Account) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// AfterFind is a method hook that re-encodes the stored balance so callers only
+// ever see the per-currency list, even for accounts that still hold a legacy
+// single-currency balance
+func (a *Account) AfterFind(tx *gorm.DB) (err error) {
+	if len(a.Balance) == 0 {
+		return nil
+	}
+
+	var bal AccountBalances
+	err = json.Unmarshal(a.Balance, &bal)
+	if err != nil {
+		return err
+	}
+
+	a.Balance, err = bal.Stored()
+	return err
+}
+
+// MarshalJSON encodes the account with its balances as responses show them, with
+// their posted, available and formatted amounts
+func (a Account) MarshalJSON() ([]byte, error) {
+	type plain Account
+	balance, err := balanceView(a.Balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Balance json.RawMessage `json:"balance"`
+	}{plain(a), balance})
+}
+
 // New is a constructor that returns a new instance of Account
 func (a *Account) New(id string) *Account {
 	return &Account{
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestStoredBalancesLeaveOutDerivedAmounts(t *testing.T) {
+	bal := AccountBalances{{Amount: money.NewAmount(1234), Held: money.NewAmount(34), Currency: "USD"}}
+
+	stored, err := bal.Stored()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"amount":"1234","held":"34","currency":"USD"}]`, string(stored))
+
+	// Responses derive them from what is stored
+	acct := Account{ID: "acct", Balance: stored, Version: 2}
+	b, err := json.Marshal(acct)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"acct","name":"","description":"","version":2,"balance":[{"amount":"1234","held":"34","currency":"USD",`+
+		`"posted":"1234","available":"1200","formatted":"12.34 USD"}]}`, string(b))
+
+	// Derived amounts written by older versions are dropped when the balance is stored again
+	var read AccountBalances
+	assert.NoError(t, json.Unmarshal([]byte(`[{"amount":"5","held":"0","currency":"USD","posted":"9","available":"9","formatted":"0.09 USD"}]`), &read))
+	stored, err = read.Stored()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"amount":"5","held":"0","currency":"USD"}]`, string(stored))
+}
+
+func TestStoredMoneyLeavesOutFormattedAmount(t *testing.T) {
+	stored, err := TransactionMoney{Amount: money.NewAmount(500), Currency: "JPY"}.Stored()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"500","currency":"JPY"}`, string(stored))
+
+	tx := Transaction{ID: "tx", Money: stored, State: TransactionStateCompleted}
+	b, err := json.Marshal(&tx)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"money":{"amount":"500","currency":"JPY","formatted":"500 JPY"}`)
+
+	snapshot, err := tx.Snapshot()
+	assert.NoError(t, err)
+	assert.Contains(t, string(snapshot), `"money":{"amount":"500","currency":"JPY"}`)
+
+	hold := Hold{ID: "hold", Money: stored}
+	b, err = json.Marshal(hold)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"money":{"amount":"500","currency":"JPY","formatted":"500 JPY"}`)
+	assert.NotContains(t, string(b), "captured_money")
+}
+
+func TestLegacyReversedAtStaysOutOfResponses(t *testing.T) {
+	at := time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	tx := Transaction{ID: "tx", Money: datatypes.JSON(`{"amount":"1","currency":"USD"}`), State: TransactionStateReversed, ReversedAt: &at}
+
+	b, err := json.Marshal(&tx)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "reversed_at")
+}
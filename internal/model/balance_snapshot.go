@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// BalanceSnapshot is the model for an account's posted balances as they stood at TakenAt.
+// Historical balance lookups start from the nearest snapshot and only replay the
+// transactions posted after it. Snapshots are never updated.
+type BalanceSnapshot struct {
+	gorm.Model     `json:"-"`
+	ID             string         `gorm:"primaryKey;uniqueIndex" json:"id"`
+	AccountID      string         `gorm:"index:idx_balance_snapshots_account_taken" json:"account_id"`
+	TakenAt        time.Time      `gorm:"index:idx_balance_snapshots_account_taken" json:"taken_at"`
+	AccountVersion uint           `json:"account_version"`
+	Balance        datatypes.JSON `json:"balance"`
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (s *BalanceSnapshot) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = ksuid.New().String()
+	return nil
+}
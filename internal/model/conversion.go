@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Conversion is the model for a currency conversion between two account balances
+type Conversion struct {
+	gorm.Model          `json:"-"`
+	ID                  string         `gorm:"primaryKey;uniqueIndex" json:"id"`
+	SourceMoney         datatypes.JSON `json:"source_money"`
+	TargetMoney         datatypes.JSON `json:"target_money"`
+	Rate                string         `json:"rate"`
+	Remainder           string         `json:"remainder"`
+	Memo                string         `json:"memo,omitempty"`
+	FromAccountID       string         `json:"from_account_id"`
+	ToAccountID         string         `json:"to_account_id"`
+	DebitTransactionID  string         `json:"debit_transaction_id"`
+	CreditTransactionID string         `json:"credit_transaction_id"`
+}
+
+// CreateConversionRequest is the model for a conversion create request.
+// ToAccountID defaults to FromAccountID to convert within one account.
+type CreateConversionRequest struct {
+	Money          TransactionMoney `binding:"required" json:"money"`
+	TargetCurrency string           `binding:"required,currency" json:"target_currency"`
+	Memo           string           `json:"memo,omitempty"`
+	FromAccountID  string           `binding:"required" json:"from_account_id"`
+	ToAccountID    string           `json:"to_account_id,omitempty"`
+}
+
+// MarshalJSON encodes the conversion with its source and target money as
+// responses show them
+func (c Conversion) MarshalJSON() ([]byte, error) {
+	type plain Conversion
+	source, err := moneyView(c.SourceMoney)
+	if err != nil {
+		return nil, err
+	}
+	target, err := moneyView(c.TargetMoney)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		SourceMoney json.RawMessage `json:"source_money"`
+		TargetMoney json.RawMessage `json:"target_money"`
+	}{plain(c), source, target})
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (c *Conversion) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = ksuid.New().String()
+	return nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/gorm"
+)
+
+// TransactionEvent is the model for one recorded change of a transaction's state.
+// An empty FromState is the transaction being created. Sequence numbers events in
+// the order they were written, since ids only sort to the second and events written
+// together can share an OccurredAt.
+type TransactionEvent struct {
+	gorm.Model    `json:"-"`
+	ID            string           `gorm:"primaryKey;uniqueIndex" json:"id"`
+	TransactionID string           `gorm:"index" json:"transaction_id"`
+	FromState     TransactionState `json:"from_state,omitempty"`
+	ToState       TransactionState `json:"to_state"`
+	Actor         string           `json:"actor"`
+	Reason        string           `json:"reason,omitempty"`
+	OccurredAt    time.Time        `json:"occurred_at"`
+	Sequence      uint64           `gorm:"autoIncrement;index" json:"-"`
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (e *TransactionEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = ksuid.New().String()
+	return nil
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// HoldState is an enum for hold state
+type HoldState string
+
+const (
+	// HoldStateActive is a hold that still reserves its funds
+	HoldStateActive HoldState = "ACTIVE"
+	// HoldStateCaptured is a hold that was fully or partially captured
+	HoldStateCaptured HoldState = "CAPTURED"
+	// HoldStateVoided is a hold whose funds were released without capture
+	HoldStateVoided HoldState = "VOIDED"
+	// HoldStateExpired is a hold whose funds were released when it passed its expiry
+	HoldStateExpired HoldState = "EXPIRED"
+)
+
+// Hold is the model for an authorization hold. It reserves funds from an
+// account's available balance without changing its posted balance.
+type Hold struct {
+	gorm.Model           `json:"-"`
+	ID                   string         `gorm:"primaryKey;uniqueIndex" json:"id"`
+	AccountID            string         `gorm:"index" json:"account_id"`
+	Money                datatypes.JSON `json:"money"`
+	CapturedMoney        datatypes.JSON `json:"captured_money,omitempty"`
+	Memo                 string         `json:"memo,omitempty"`
+	State                HoldState      `json:"state"`
+	CaptureTransactionID string         `json:"capture_transaction_id,omitempty"`
+	ExpiresAt            *time.Time     `gorm:"index" json:"expires_at,omitempty"`
+	Reason               string         `json:"reason,omitempty"`
+}
+
+// CreateHoldRequest is the model for a hold create request
+type CreateHoldRequest struct {
+	Money     TransactionMoney `binding:"required" json:"money"`
+	Memo      string           `json:"memo,omitempty"`
+	AccountID string           `binding:"required" json:"account_id"`
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+}
+
+// CaptureHoldRequest is the model for a hold capture request.
+// Leaving out Amount captures the full authorized amount.
+type CaptureHoldRequest struct {
+	Amount *money.Amount `binding:"omitempty,amount_gt0" json:"amount,omitempty"`
+	Memo   string        `json:"memo,omitempty"`
+}
+
+// MarshalJSON encodes the hold with its authorized and captured money as
+// responses show them
+func (h Hold) MarshalJSON() ([]byte, error) {
+	type plain Hold
+	m, err := moneyView(h.Money)
+	if err != nil {
+		return nil, err
+	}
+	captured, err := moneyView(h.CapturedMoney)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Money         json.RawMessage `json:"money"`
+		CapturedMoney json.RawMessage `json:"captured_money,omitempty"`
+	}{plain(h), m, captured})
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (h *Hold) BeforeCreate(tx *gorm.DB) (err error) {
+	h.ID = ksuid.New().String()
+	return nil
+}
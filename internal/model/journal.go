@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// JournalEntry is the model for a multi-leg journal entry
+type JournalEntry struct {
+	gorm.Model `json:"-"`
+	ID         string    `gorm:"primaryKey;uniqueIndex" json:"id"`
+	Memo       string    `json:"memo,omitempty"`
+	Postings   []Posting `gorm:"foreignKey:JournalEntryID" json:"postings"`
+}
+
+// Posting is the model for a single debit or credit leg of a journal entry
+type Posting struct {
+	gorm.Model     `json:"-"`
+	ID             string               `gorm:"primaryKey;uniqueIndex" json:"id"`
+	JournalEntryID string               `gorm:"index" json:"journal_entry_id"`
+	AccountID      string               `json:"account_id"`
+	Direction      TransactionDirection `json:"direction"`
+	Money          datatypes.JSON       `json:"money"`
+	Memo           string               `json:"memo,omitempty"`
+	TransactionID  string               `json:"transaction_id"`
+}
+
+// CreatePostingRequest is the model for a single leg of a journal entry create request
+type CreatePostingRequest struct {
+	Money     TransactionMoney     `binding:"required" json:"money"`
+	Memo      string               `json:"memo,omitempty"`
+	Direction TransactionDirection `binding:"required" json:"direction"`
+	AccountID string               `binding:"required" json:"account_id"`
+}
+
+// CreateJournalEntryRequest is the model for a journal entry create request
+type CreateJournalEntryRequest struct {
+	Memo     string                 `json:"memo,omitempty"`
+	Postings []CreatePostingRequest `binding:"required,min=2,dive" json:"postings"`
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (j *JournalEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	j.ID = ksuid.New().String()
+	return nil
+}
+
+// MarshalJSON encodes the posting with its money as responses show it
+func (p Posting) MarshalJSON() ([]byte, error) {
+	type plain Posting
+	m, err := moneyView(p.Money)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Money json.RawMessage `json:"money"`
+	}{plain(p), m})
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (p *Posting) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = ksuid.New().String()
+	return nil
+}
@@ -0,0 +1,41 @@
+package model
+
+import "github.com/partyscript/bledger/internal/money"
+
+// DailyBalancesRequest is the model for a daily balance series query. From and To are
+// inclusive local dates in TZ, an IANA time zone name that defaults to UTC.
+type DailyBalancesRequest struct {
+	From string `binding:"required,datetime=2006-01-02" form:"from"`
+	To   string `binding:"required,datetime=2006-01-02" form:"to"`
+	TZ   string `form:"tz"`
+}
+
+// DailyBalance is the model for one currency's posted balance over one local day.
+// Credits and Debits are what was posted that day; a legacy in-place reversal counts
+// as a movement in the opposite direction on the day it was reversed.
+type DailyBalance struct {
+	Date     string       `json:"date"`
+	Currency string       `json:"currency"`
+	Opening  money.Amount `json:"opening"`
+	Credits  money.Amount `json:"credits"`
+	Debits   money.Amount `json:"debits"`
+	Closing  money.Amount `json:"closing"`
+}
+
+// DailyBalanceSeries is the model for an account's daily balances over a date range
+type DailyBalanceSeries struct {
+	AccountID string         `json:"account_id"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	TZ        string         `json:"tz"`
+	Days      []DailyBalance `json:"days"`
+}
+
+// StatementRequest is the model for an account statement query. From and To are
+// inclusive local dates in TZ, an IANA time zone name that defaults to UTC.
+type StatementRequest struct {
+	From   string `binding:"required,datetime=2006-01-02" form:"from"`
+	To     string `binding:"required,datetime=2006-01-02" form:"to"`
+	TZ     string `form:"tz"`
+	Format string `binding:"omitempty,oneof=csv jsonl txt camt053" form:"format"`
+}
@@ -1,6 +1,10 @@
 package model
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
 	"github.com/segmentio/ksuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -26,27 +30,84 @@ const (
 	TransactionStateCompleted TransactionState = "COMPLETED"
 	// TransactionStateFailed is a failed transaction
 	TransactionStateFailed TransactionState = "FAILED"
-	// TransactionStateReversed is a reversed transaction
+	// TransactionStateReversed is a transaction reversed in place before reversals
+	// were written as separate transactions. New reversals don't use it.
 	TransactionStateReversed TransactionState = "REVERSED"
+	// TransactionStateExpired is a pending transaction that passed its expiry
+	TransactionStateExpired TransactionState = "EXPIRED"
+	// TransactionStateVoided is a pending transaction that was cancelled
+	TransactionStateVoided TransactionState = "VOIDED"
+)
+
+// BatchMode is an enum for how a transaction batch treats failed items
+type BatchMode string
+
+const (
+	// BatchModeAtomic commits every item in a batch or none of them
+	BatchModeAtomic BatchMode = "ATOMIC"
+	// BatchModeBestEffort commits the items that succeed and reports the ones that fail
+	BatchModeBestEffort BatchMode = "BEST_EFFORT"
 )
 
-// Transaction is the model for a transaction
+// TransactionFailureCode is an enum for why a processor failed a transaction
+type TransactionFailureCode string
+
+const (
+	// TransactionFailureCodeDeclined is a transaction the processor declined
+	TransactionFailureCodeDeclined TransactionFailureCode = "DECLINED"
+	// TransactionFailureCodeInsufficientFunds is a transaction the counterparty couldn't fund
+	TransactionFailureCodeInsufficientFunds TransactionFailureCode = "INSUFFICIENT_FUNDS"
+	// TransactionFailureCodeInvalidAccount is a transaction to or from an unknown counterparty account
+	TransactionFailureCodeInvalidAccount TransactionFailureCode = "INVALID_ACCOUNT"
+	// TransactionFailureCodeAccountClosed is a transaction to or from a closed counterparty account
+	TransactionFailureCodeAccountClosed TransactionFailureCode = "ACCOUNT_CLOSED"
+	// TransactionFailureCodeSuspectedFraud is a transaction stopped by fraud checks
+	TransactionFailureCodeSuspectedFraud TransactionFailureCode = "SUSPECTED_FRAUD"
+	// TransactionFailureCodeProcessorError is a transaction the processor couldn't handle
+	TransactionFailureCodeProcessorError TransactionFailureCode = "PROCESSOR_ERROR"
+	// TransactionFailureCodeOther is any other failure, described by the reason
+	TransactionFailureCodeOther TransactionFailureCode = "OTHER"
+)
+
+// Transaction is the model for a transaction. Once it leaves PENDING its row is never
+// updated: ReversedBy and RefundedAmount aren't stored on it but read from the reversal
+// and refunds that point back at it.
 type Transaction struct {
-	gorm.Model  `json:"-"`
-	ID          string               `gorm:"primaryKey;uniqueIndex" json:"id"`
-	Money       datatypes.JSON       `json:"money"`
-	Memo        string               `json:"memo,omitempty"`
-	Direction   TransactionDirection `json:"direction"`
-	AccountID   string               `json:"account_id"`
-	Version     uint                 `json:"version"`
-	State       TransactionState     `json:"state" gorm:"default:'PENDING'"`
-	ErrorReason string               `json:"error_reason,omitempty"`
-}
-
-// TransactionMoney is the model for a transaction money
+	gorm.Model            `json:"-"`
+	ID                    string                 `gorm:"primaryKey;uniqueIndex" json:"id"`
+	Money                 datatypes.JSON         `json:"money"`
+	Memo                  string                 `json:"memo,omitempty"`
+	Direction             TransactionDirection   `json:"direction"`
+	AccountID             string                 `json:"account_id"`
+	Version               uint                   `json:"version"`
+	State                 TransactionState       `json:"state" gorm:"default:'PENDING'"`
+	ErrorReason           string                 `json:"error_reason,omitempty"`
+	ErrorCode             TransactionFailureCode `json:"error_code,omitempty"`
+	TransferID            string                 `gorm:"index" json:"transfer_id,omitempty"`
+	JournalEntryID        string                 `gorm:"index" json:"journal_entry_id,omitempty"`
+	ConversionID          string                 `gorm:"index" json:"conversion_id,omitempty"`
+	HoldID                string                 `gorm:"index" json:"hold_id,omitempty"`
+	FundsHeld             bool                   `json:"funds_held,omitempty"`
+	CompletedAt           *time.Time             `gorm:"index" json:"completed_at,omitempty"`
+	ReversesTransactionID string                 `gorm:"index" json:"reverses_transaction_id,omitempty"`
+	ReversedBy            string                 `gorm:"column:reversal_id;->;-:migration" json:"reversed_by,omitempty"`
+	RefundOfTransactionID string                 `gorm:"index" json:"refund_of_transaction_id,omitempty"`
+	RefundedAmount        money.Amount           `gorm:"column:refunded_total;->;-:migration" json:"refunded_amount"`
+	ExpiresAt             *time.Time             `gorm:"index" json:"expires_at,omitempty"`
+	FailedAt              *time.Time             `json:"failed_at,omitempty"`
+	VoidedAt              *time.Time             `json:"voided_at,omitempty"`
+
+	// ReversedAt is only set on legacy REVERSED rows, from before reversals were
+	// posted as their own transactions. Nothing writes it any more; it is kept to
+	// date when those rows were undone, falling back to UpdatedAt where it is missing.
+	ReversedAt *time.Time `json:"-"`
+}
+
+// TransactionMoney is the model for a transaction money. Amount is in the
+// currency's ISO 4217 minor unit, e.g. cents for USD, and is a JSON string.
 type TransactionMoney struct {
-	Amount   uint64 `binding:"required" gorm:"-" json:"amount"`
-	Currency string `binding:"required" gorm:"-" json:"currency"`
+	Amount   money.Amount `binding:"amount_gt0" gorm:"-" json:"amount"`
+	Currency string       `binding:"required,currency" gorm:"-" json:"currency"`
 }
 
 // CreateTransactionRequest is the model for a transaction create request
@@ -55,6 +116,58 @@ type CreateTransactionRequest struct {
 	Memo      string               `binding:"required" json:"memo,omitempty"`
 	Direction TransactionDirection `binding:"required" json:"direction"`
 	AccountID string               `binding:"required" json:"account_id"`
+	ExpiresAt *time.Time           `json:"expires_at,omitempty"`
+}
+
+// CreateTransactionBatchRequest is the model for a batch of immediate transactions
+type CreateTransactionBatchRequest struct {
+	Mode  BatchMode                  `binding:"required,oneof=ATOMIC BEST_EFFORT" json:"mode"`
+	Items []CreateTransactionRequest `binding:"required,min=1,dive" json:"items"`
+}
+
+// TransactionBatchResult is the model for the outcome of one item in a batch.
+// Index is the item's position in the request.
+type TransactionBatchResult struct {
+	Index       int          `json:"index"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// TransactionBatch is the model for a committed batch of transactions
+type TransactionBatch struct {
+	Mode      BatchMode                `json:"mode"`
+	Succeeded int                      `json:"succeeded"`
+	Failed    int                      `json:"failed"`
+	Results   []TransactionBatchResult `json:"results"`
+}
+
+// FailTransactionRequest is the model for a request to fail a pending transaction
+type FailTransactionRequest struct {
+	ReasonCode TransactionFailureCode `binding:"required,oneof=DECLINED INSUFFICIENT_FUNDS INVALID_ACCOUNT ACCOUNT_CLOSED SUSPECTED_FRAUD PROCESSOR_ERROR OTHER" json:"reason_code"`
+	Reason     string                 `json:"reason,omitempty"`
+}
+
+// CreateRefundRequest is the model for a request to refund part or all of a
+// completed transaction. Amount is in the original transaction's currency.
+type CreateRefundRequest struct {
+	Amount money.Amount `binding:"amount_gt0" json:"amount"`
+	Memo   string       `json:"memo,omitempty"`
+}
+
+// ListTransactionsRequest is the model for an account transaction listing query
+type ListTransactionsRequest struct {
+	State         TransactionState     `binding:"omitempty,oneof=PENDING COMPLETED FAILED REVERSED EXPIRED VOIDED" form:"state"`
+	Direction     TransactionDirection `binding:"omitempty,oneof=DEBIT CREDIT" form:"direction"`
+	CreatedAfter  *time.Time           `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time           `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor        string               `form:"cursor"`
+	Limit         int                  `binding:"omitempty,min=1,max=100" form:"limit"`
+}
+
+// TransactionPage is the model for a page of transactions
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
 }
 
 // BeforeCreate is a method hook that generates a custom sorted id
@@ -62,3 +175,62 @@ func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
 	t.ID = ksuid.New().String()
 	return nil
 }
+
+// MarshalJSON encodes the transaction with its money as responses show it, with
+// its formatted amount
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type plain Transaction
+	m, err := moneyView(t.Money)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Money json.RawMessage `json:"money"`
+	}{plain(t), m})
+}
+
+// Snapshot encodes the transaction as it is stored, for its version history
+func (t Transaction) Snapshot() (datatypes.JSON, error) {
+	type plain Transaction
+	data, err := json.Marshal(plain(t))
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// Stored encodes the money as it is stored, without its formatted amount
+func (m TransactionMoney) Stored() (datatypes.JSON, error) {
+	type stored TransactionMoney
+	data, err := json.Marshal(stored(m))
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// moneyView re-encodes stored money for a response
+func moneyView(stored datatypes.JSON) (json.RawMessage, error) {
+	if len(stored) == 0 {
+		return nil, nil
+	}
+
+	var m TransactionMoney
+	err := json.Unmarshal(stored, &m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// MarshalJSON encodes the money for a response, alongside its amount formatted
+// in major units
+func (m TransactionMoney) MarshalJSON() ([]byte, error) {
+	type plain TransactionMoney
+	return json.Marshal(struct {
+		plain
+		Formatted string `json:"formatted,omitempty"`
+	}{plain(m), money.Format(m.Amount, m.Currency)})
+}
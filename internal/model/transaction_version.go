@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TransactionVersion is the model for an immutable snapshot of a transaction after one
+// change. Versions share the logical transaction id and are never updated.
+type TransactionVersion struct {
+	gorm.Model    `json:"-"`
+	ID            string           `gorm:"primaryKey;uniqueIndex" json:"id"`
+	TransactionID string           `gorm:"uniqueIndex:idx_transaction_versions_transaction_version" json:"transaction_id"`
+	Version       uint             `gorm:"uniqueIndex:idx_transaction_versions_transaction_version" json:"version"`
+	State         TransactionState `json:"state"`
+	Snapshot      datatypes.JSON   `json:"snapshot"`
+	RecordedAt    time.Time        `json:"recorded_at"`
+}
+
+// GetTransactionRequest is the model for a transaction lookup query.
+// Leaving out Version returns the current version.
+type GetTransactionRequest struct {
+	Version uint `binding:"omitempty,min=1" form:"version"`
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (v *TransactionVersion) BeforeCreate(tx *gorm.DB) (err error) {
+	v.ID = ksuid.New().String()
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/segmentio/ksuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Transfer is the model for a double-entry transfer between two accounts
+type Transfer struct {
+	gorm.Model          `json:"-"`
+	ID                  string         `gorm:"primaryKey;uniqueIndex" json:"id"`
+	Money               datatypes.JSON `json:"money"`
+	Memo                string         `json:"memo,omitempty"`
+	FromAccountID       string         `json:"from_account_id"`
+	ToAccountID         string         `json:"to_account_id"`
+	DebitTransactionID  string         `json:"debit_transaction_id"`
+	CreditTransactionID string         `json:"credit_transaction_id"`
+}
+
+// CreateTransferRequest is the model for a transfer create request
+type CreateTransferRequest struct {
+	Money         TransactionMoney `binding:"required" json:"money"`
+	Memo          string           `json:"memo,omitempty"`
+	FromAccountID string           `binding:"required" json:"from_account_id"`
+	ToAccountID   string           `binding:"required,nefield=FromAccountID" json:"to_account_id"`
+}
+
+// MarshalJSON encodes the transfer with its money as responses show it
+func (t Transfer) MarshalJSON() ([]byte, error) {
+	type plain Transfer
+	m, err := moneyView(t.Money)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Money json.RawMessage `json:"money"`
+	}{plain(t), m})
+}
+
+// BeforeCreate is a method hook that generates a custom sorted id
+func (t *Transfer) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = ksuid.New().String()
+	return nil
+}
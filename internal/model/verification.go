@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// BalanceDrift is the model for a currency balance whose stored amounts don't match
+// the amounts replayed from the account's history. An overdrawn drift is a history that
+// debits Shortfall more than it credits, so there is no expected posted amount to repair to.
+type BalanceDrift struct {
+	AccountID      string        `json:"account_id"`
+	Currency       string        `json:"currency"`
+	StoredPosted   money.Amount  `json:"stored_posted"`
+	ExpectedPosted money.Amount  `json:"expected_posted"`
+	StoredHeld     money.Amount  `json:"stored_held"`
+	ExpectedHeld   money.Amount  `json:"expected_held"`
+	Overdrawn      bool          `json:"overdrawn,omitempty"`
+	Shortfall      *money.Amount `json:"shortfall,omitempty"`
+	Repaired       bool          `json:"repaired"`
+}
+
+// VerificationReport is the model for the result of a balance verification run
+type VerificationReport struct {
+	StartedAt        time.Time      `json:"started_at"`
+	FinishedAt       time.Time      `json:"finished_at"`
+	Repair           bool           `json:"repair"`
+	AccountsChecked  int            `json:"accounts_checked"`
+	AccountsDrifted  int            `json:"accounts_drifted"`
+	AccountsRepaired int            `json:"accounts_repaired"`
+	Drifts           []BalanceDrift `json:"drifts"`
+}
@@ -0,0 +1,170 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrAmountOverflow is returned when a result exceeds MaxAmount
+	ErrAmountOverflow = errors.New("amount overflows the maximum supported amount")
+
+	// ErrAmountUnderflow is returned when a result would be negative
+	ErrAmountUnderflow = errors.New("amount cannot be negative")
+
+	// MaxAmount is the largest supported amount, 2^256-1, which covers
+	// 18-decimal assets held in uint256 on chain
+	MaxAmount = Amount{i: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))}
+)
+
+// Amount is a non-negative, arbitrary-precision quantity of a currency's minor
+// unit. The zero value is zero. Amounts are immutable; arithmetic returns a new
+// Amount. It encodes to JSON as a string so clients don't lose precision.
+type Amount struct {
+	i *big.Int
+}
+
+// NewAmount returns an Amount from a uint64
+func NewAmount(v uint64) Amount {
+	return Amount{i: new(big.Int).SetUint64(v)}
+}
+
+// ParseAmount parses a base 10 integer amount
+func ParseAmount(s string) (Amount, error) {
+	if s == "" {
+		return Amount{}, errors.New("amount cannot be empty")
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return Amount{}, fmt.Errorf("invalid amount %q, must be an integer in minor units", s)
+		}
+	}
+
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	a := Amount{i: i}
+	if a.Cmp(MaxAmount) > 0 {
+		return Amount{}, ErrAmountOverflow
+	}
+
+	return a, nil
+}
+
+// BigInt returns a copy of the amount as a big.Int
+func (a Amount) BigInt() *big.Int {
+	if a.i == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.i)
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a.i == nil || a.i.Sign() == 0
+}
+
+// Cmp compares two amounts and returns -1, 0 or +1
+func (a Amount) Cmp(b Amount) int {
+	return a.BigInt().Cmp(b.BigInt())
+}
+
+// Add returns a+b, or ErrAmountOverflow if it exceeds MaxAmount
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum := new(big.Int).Add(a.BigInt(), b.BigInt())
+	if sum.Cmp(MaxAmount.i) > 0 {
+		return Amount{}, ErrAmountOverflow
+	}
+	return Amount{i: sum}, nil
+}
+
+// Sub returns a-b, or ErrAmountUnderflow if b is larger than a
+func (a Amount) Sub(b Amount) (Amount, error) {
+	diff := new(big.Int).Sub(a.BigInt(), b.BigInt())
+	if diff.Sign() < 0 {
+		return Amount{}, ErrAmountUnderflow
+	}
+	return Amount{i: diff}, nil
+}
+
+// String returns the amount in base 10
+func (a Amount) String() string {
+	return a.BigInt().String()
+}
+
+// MarshalJSON encodes the amount as a JSON string
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an amount from a JSON string, or from a JSON number as
+// stored by records written before amounts were arbitrary precision
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(bytes.TrimSpace(data))
+	if s == "null" {
+		return nil
+	}
+
+	if len(s) > 0 && s[0] == '"' {
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+	return nil
+}
+
+// GormDataType stores amounts as an exact numeric column
+func (Amount) GormDataType() string {
+	return "numeric"
+}
+
+// Value stores the amount as a base 10 string so the database keeps every digit
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan reads an amount from a numeric, text or integer column. NULL is zero.
+func (a *Amount) Scan(src interface{}) error {
+	var s string
+
+	switch v := src.(type) {
+	case nil:
+		*a = Amount{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		if v < 0 {
+			return ErrAmountUnderflow
+		}
+		*a = NewAmount(uint64(v))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into an amount", src)
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+	return nil
+}
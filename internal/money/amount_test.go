@@ -0,0 +1,98 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	a, err := ParseAmount("123456789012345678901234567890")
+	assert.NoError(t, err)
+	assert.Equal(t, "123456789012345678901234567890", a.String())
+
+	for _, bad := range []string{"", "-1", "1.5", "1e3", " 1", "abc"} {
+		_, err := ParseAmount(bad)
+		assert.Error(t, err, bad)
+	}
+
+	_, err = ParseAmount(MaxAmount.String() + "0")
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	var zero Amount
+	assert.True(t, zero.IsZero())
+	assert.Equal(t, "0", zero.String())
+
+	sum, err := NewAmount(18446744073709551615).Add(NewAmount(1))
+	assert.NoError(t, err)
+	assert.Equal(t, "18446744073709551616", sum.String())
+
+	diff, err := sum.Sub(NewAmount(16))
+	assert.NoError(t, err)
+	assert.Equal(t, "18446744073709551600", diff.String())
+	assert.Equal(t, 1, sum.Cmp(diff))
+
+	_, err = NewAmount(1).Sub(NewAmount(2))
+	assert.ErrorIs(t, err, ErrAmountUnderflow)
+
+	_, err = MaxAmount.Add(NewAmount(1))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+
+	// Arithmetic never mutates its operands
+	a := NewAmount(5)
+	_, _ = a.Add(NewAmount(5))
+	assert.Equal(t, "5", a.String())
+}
+
+func TestAmountJSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{NewAmount(42)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":"42"}`, string(b))
+
+	var v struct {
+		Amount Amount `json:"amount"`
+	}
+
+	err = json.Unmarshal([]byte(`{"amount":"1000000000000000000000"}`), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, "1000000000000000000000", v.Amount.String())
+
+	// Numbers are accepted for records stored before amounts were strings
+	err = json.Unmarshal([]byte(`{"amount":250}`), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, "250", v.Amount.String())
+
+	err = json.Unmarshal([]byte(`{"amount":2.5}`), &v)
+	assert.Error(t, err)
+
+	err = json.Unmarshal([]byte(`{"amount":"-3"}`), &v)
+	assert.Error(t, err)
+}
+
+func TestAmountSQL(t *testing.T) {
+	v, err := MaxAmount.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, MaxAmount.String(), v)
+
+	var a Amount
+	assert.NoError(t, a.Scan([]byte("1000000000000000000000")))
+	assert.Equal(t, "1000000000000000000000", a.String())
+
+	assert.NoError(t, a.Scan("12"))
+	assert.Equal(t, "12", a.String())
+
+	assert.NoError(t, a.Scan(int64(7)))
+	assert.Equal(t, "7", a.String())
+
+	assert.NoError(t, a.Scan(nil))
+	assert.True(t, a.IsZero())
+
+	assert.Error(t, a.Scan(int64(-1)))
+	assert.Error(t, a.Scan("1.5"))
+	assert.Error(t, a.Scan(1.5))
+}
@@ -0,0 +1,134 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Currency is a currency code and the number of digits in its minor unit
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// iso4217 maps every active ISO 4217 currency code to its minor unit exponent
+var iso4217 = map[string]int{
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2,
+	"ARS": 2, "AUD": 2, "AWG": 2, "AZN": 2, "BAM": 2, "BBD": 2,
+	"BDT": 2, "BGN": 2, "BHD": 3, "BIF": 0, "BMD": 2, "BND": 2,
+	"BOB": 2, "BOV": 2, "BRL": 2, "BSD": 2, "BTN": 2, "BWP": 2,
+	"BYN": 2, "BZD": 2, "CAD": 2, "CDF": 2, "CHE": 2, "CHF": 2,
+	"CHW": 2, "CLF": 4, "CLP": 0, "CNY": 2, "COP": 2, "COU": 2,
+	"CRC": 2, "CUC": 2, "CUP": 2, "CVE": 2, "CZK": 2, "DJF": 0,
+	"DKK": 2, "DOP": 2, "DZD": 2, "EGP": 2, "ERN": 2, "ETB": 2,
+	"EUR": 2, "FJD": 2, "FKP": 2, "GBP": 2, "GEL": 2, "GHS": 2,
+	"GIP": 2, "GMD": 2, "GNF": 0, "GTQ": 2, "GYD": 2, "HKD": 2,
+	"HNL": 2, "HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"IQD": 3, "IRR": 2, "ISK": 0, "JMD": 2, "JOD": 3, "JPY": 0,
+	"KES": 2, "KGS": 2, "KHR": 2, "KMF": 0, "KPW": 2, "KRW": 0,
+	"KWD": 3, "KYD": 2, "KZT": 2, "LAK": 2, "LBP": 2, "LKR": 2,
+	"LRD": 2, "LSL": 2, "LYD": 3, "MAD": 2, "MDL": 2, "MGA": 2,
+	"MKD": 2, "MMK": 2, "MNT": 2, "MOP": 2, "MRU": 2, "MUR": 2,
+	"MVR": 2, "MWK": 2, "MXN": 2, "MXV": 2, "MYR": 2, "MZN": 2,
+	"NAD": 2, "NGN": 2, "NIO": 2, "NOK": 2, "NPR": 2, "NZD": 2,
+	"OMR": 3, "PAB": 2, "PEN": 2, "PGK": 2, "PHP": 2, "PKR": 2,
+	"PLN": 2, "PYG": 0, "QAR": 2, "RON": 2, "RSD": 2, "RUB": 2,
+	"RWF": 0, "SAR": 2, "SBD": 2, "SCR": 2, "SDG": 2, "SEK": 2,
+	"SGD": 2, "SHP": 2, "SLE": 2, "SLL": 2, "SOS": 2, "SRD": 2,
+	"SSP": 2, "STN": 2, "SVC": 2, "SYP": 2, "SZL": 2, "THB": 2,
+	"TJS": 2, "TMT": 2, "TND": 3, "TOP": 2, "TRY": 2, "TTD": 2,
+	"TWD": 2, "TZS": 2, "UAH": 2, "UGX": 0, "USD": 2, "USN": 2,
+	"UYI": 0, "UYU": 2, "UYW": 4, "UZS": 2, "VED": 2, "VES": 2,
+	"VND": 0, "VUV": 0, "WST": 2, "XAF": 0, "XCD": 2, "XOF": 0,
+	"XPF": 0, "YER": 2, "ZAR": 2, "ZMW": 2, "ZWL": 2,
+}
+
+// assets maps the high-precision digital assets that have no ISO 4217 code to
+// the exponent of their smallest unit, e.g. wei for ETH
+var assets = map[string]int{
+	"BTC": 8, "ETH": 18, "SOL": 9, "USDC": 6, "USDT": 6,
+}
+
+// Lookup returns the currency for a code
+func Lookup(code string) (Currency, bool) {
+	exp, ok := iso4217[code]
+	if !ok {
+		exp, ok = assets[code]
+	}
+	if !ok {
+		return Currency{}, false
+	}
+	return Currency{Code: code, Exponent: exp}, true
+}
+
+// ValidateCurrency returns an error unless code is a known currency code
+func ValidateCurrency(code string) error {
+	if _, ok := Lookup(code); !ok {
+		return fmt.Errorf("unknown currency %v", code)
+	}
+	return nil
+}
+
+// FormatAmount renders an amount in minor units as a decimal in major units,
+// e.g. 1234 with exponent 2 is 12.34
+func FormatAmount(amount Amount, exponent int) string {
+	digits := amount.String()
+	if exponent <= 0 {
+		return digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// ParseFormattedAmount parses a decimal in major units into minor units, e.g. 12.34
+// with exponent 2 is 1234. It rejects more decimals than the currency has.
+func ParseFormattedAmount(s string, exponent int) (Amount, error) {
+	whole, frac, point := strings.Cut(s, ".")
+	if whole == "" || (point && frac == "") || strings.Trim(whole+frac, "0123456789") != "" {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > exponent {
+		return Amount{}, fmt.Errorf("amount %v has more than %v decimals", s, exponent)
+	}
+
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", exponent-len(frac)), "0")
+	if digits == "" {
+		return Amount{}, nil
+	}
+
+	return ParseAmount(digits)
+}
+
+// Format renders an amount in minor units with its currency code, e.g. 12.34 USD.
+// It returns an empty string for unknown currencies.
+func Format(amount Amount, code string) string {
+	cur, ok := Lookup(code)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v %v", FormatAmount(amount, cur.Exponent), cur.Code)
+}
+
+// MinorUnitScale returns the factor that converts a rate quoted per major unit of
+// `from` into one per minor unit, e.g. 1/100 from USD to JPY
+func MinorUnitScale(from string, to string) (*big.Rat, error) {
+	f, ok := Lookup(from)
+	if !ok {
+		return nil, fmt.Errorf("unknown currency %v", from)
+	}
+
+	t, ok := Lookup(to)
+	if !ok {
+		return nil, fmt.Errorf("unknown currency %v", to)
+	}
+
+	num := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Exponent)), nil)
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(f.Exponent)), nil)
+
+	return new(big.Rat).SetFrac(num, denom), nil
+}
@@ -0,0 +1,92 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	testCases := []struct {
+		code     string
+		exponent int
+	}{
+		{code: "JPY", exponent: 0},
+		{code: "USD", exponent: 2},
+		{code: "EUR", exponent: 2},
+		{code: "BHD", exponent: 3},
+		{code: "CLF", exponent: 4},
+		{code: "BTC", exponent: 8},
+		{code: "ETH", exponent: 18},
+	}
+
+	for _, tc := range testCases {
+		cur, ok := Lookup(tc.code)
+		assert.True(t, ok, tc.code)
+		assert.Equal(t, tc.exponent, cur.Exponent, tc.code)
+	}
+
+	_, ok := Lookup("usd")
+	assert.False(t, ok)
+	assert.Error(t, ValidateCurrency("XXY"))
+	assert.NoError(t, ValidateCurrency("GBP"))
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "12.34 USD", Format(NewAmount(1234), "USD"))
+	assert.Equal(t, "0.05 USD", Format(NewAmount(5), "USD"))
+	assert.Equal(t, "0.00 EUR", Format(NewAmount(0), "EUR"))
+	assert.Equal(t, "1234 JPY", Format(NewAmount(1234), "JPY"))
+	assert.Equal(t, "1.234 BHD", Format(NewAmount(1234), "BHD"))
+	assert.Equal(t, "0.001 BHD", Format(NewAmount(1), "BHD"))
+	assert.Equal(t, "", Format(NewAmount(1), "NOPE"))
+
+	wei, err := ParseAmount("1500000000000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.500000000000000000 ETH", Format(wei, "ETH"))
+}
+
+func TestParseFormattedAmount(t *testing.T) {
+	testCases := []struct {
+		in       string
+		exponent int
+		want     string
+	}{
+		{in: "12.34", exponent: 2, want: "1234"},
+		{in: "12.3", exponent: 2, want: "1230"},
+		{in: "12", exponent: 2, want: "1200"},
+		{in: "0.05", exponent: 2, want: "5"},
+		{in: "0.00", exponent: 2, want: "0"},
+		{in: "1234", exponent: 0, want: "1234"},
+		{in: "1.5", exponent: 18, want: "1500000000000000000"},
+	}
+
+	for _, tc := range testCases {
+		amt, err := ParseFormattedAmount(tc.in, tc.exponent)
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, amt.String(), tc.in)
+	}
+
+	for _, in := range []string{"", ".5", "5.", "-1.00", "1,000.00", "1.2.3", "abc"} {
+		_, err := ParseFormattedAmount(in, 2)
+		assert.Error(t, err, in)
+	}
+
+	_, err := ParseFormattedAmount("1.234", 2)
+	assert.EqualError(t, err, "amount 1.234 has more than 2 decimals")
+	_, err = ParseFormattedAmount("1.5", 0)
+	assert.Error(t, err)
+}
+
+func TestMinorUnitScale(t *testing.T) {
+	scale, err := MinorUnitScale("USD", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, "1/100", scale.String())
+
+	scale, err = MinorUnitScale("JPY", "BHD")
+	assert.NoError(t, err)
+	assert.Equal(t, "1000/1", scale.String())
+
+	_, err = MinorUnitScale("USD", "NOPE")
+	assert.Error(t, err)
+}
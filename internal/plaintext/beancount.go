@@ -0,0 +1,279 @@
+package plaintext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const beancountDate = "2006-01-02"
+
+type beancount struct {
+	w io.Writer
+}
+
+func (b *beancount) open(date time.Time, account string, currencies []string, name string) error {
+	_, err := fmt.Fprintf(b.w, "%v open %v", date.UTC().Format(beancountDate), account)
+	if err != nil {
+		return err
+	}
+	if len(currencies) > 0 {
+		fmt.Fprintf(b.w, " %v", strings.Join(currencies, ","))
+	}
+	fmt.Fprintln(b.w)
+	if name != "" {
+		fmt.Fprintf(b.w, "  name: %v\n", beancountString(name))
+	}
+	_, err = fmt.Fprintln(b.w)
+	return err
+}
+
+func (b *beancount) transaction(e entry) error {
+	_, err := fmt.Fprintf(b.w, "%v * %v", e.date.UTC().Format(beancountDate), beancountString(e.memo))
+	if err != nil {
+		return err
+	}
+	for _, tag := range e.tags {
+		fmt.Fprintf(b.w, " #%v", tag)
+	}
+	for _, link := range e.links {
+		fmt.Fprintf(b.w, " ^%v", link)
+	}
+	fmt.Fprintln(b.w)
+
+	for _, kv := range e.meta {
+		fmt.Fprintf(b.w, "  %v: %v\n", kv[0], beancountString(kv[1]))
+	}
+
+	sign, other := "", "-"
+	if !e.credit {
+		sign, other = "-", ""
+	}
+	fmt.Fprintf(b.w, "  %-60v %v%v %v\n", e.account, sign, e.amount, e.currency)
+	_, err = fmt.Fprintf(b.w, "  %-60v %v%v %v\n\n", ExternalAccount, other, e.amount, e.currency)
+	return err
+}
+
+func (b *beancount) note(date time.Time, account string, text string) error {
+	_, err := fmt.Fprintf(b.w, "%v note %v %v\n\n", date.UTC().Format(beancountDate), account, beancountString(text))
+	return err
+}
+
+// beancountString quotes free text as a Beancount string on one line
+func beancountString(s string) string {
+	return `"` + beancountEscaper.Replace(oneLine(s)) + `"`
+}
+
+var beancountEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// beancountRoots are the account types a Beancount account name can start with
+var beancountRoots = []string{"Assets:", "Liabilities:", "Equity:", "Income:", "Expenses:"}
+
+// directive reads one Beancount directive. Directives that only annotate the books
+// are skipped, and ones that would post amounts the journal doesn't spell out are refused.
+func (b *beancount) directive(blk block, r *reader) error {
+	fields := strings.Fields(cutComment(blk.text))
+	if len(fields) == 0 {
+		// Only a comment, behind whitespace that doesn't count as an indent
+		return nil
+	}
+
+	switch fields[0] {
+	case "option", "pushtag", "poptag":
+		return nil
+	}
+
+	date, err := time.Parse(beancountDate, fields[0])
+	if err != nil {
+		return fmt.Errorf("%q directives are not supported", fields[0])
+	}
+	if len(fields) < 2 {
+		return errors.New("date isn't followed by a directive")
+	}
+
+	switch fields[1] {
+	case "open":
+		if len(fields) < 3 {
+			return errors.New("open has no account")
+		}
+		var currencies []string
+		if len(fields) > 3 && !strings.HasPrefix(fields[3], `"`) {
+			currencies = strings.Split(fields[3], ",")
+		}
+
+		meta, err := beancountMeta(blk.body)
+		if err != nil {
+			return err
+		}
+
+		return r.open(date, fields[2], currencies, meta["name"])
+	case "close", "commodity", "note", "price", "event", "document":
+		return nil
+	case "*", "!", "txn":
+		return b.readTransaction(date, fields[1], blk, r)
+	default:
+		return fmt.Errorf("%q directives are not supported", fields[1])
+	}
+}
+
+// readTransaction reads a transaction, its metadata and its postings
+func (b *beancount) readTransaction(date time.Time, flag string, blk block, r *reader) error {
+	header := strings.TrimSpace(cutComment(blk.text))
+	header = strings.TrimSpace(strings.TrimPrefix(header, date.Format(beancountDate)))
+	header = strings.TrimSpace(strings.TrimPrefix(header, flag))
+
+	var strs []string
+	for header != "" {
+		switch header[0] {
+		case '"':
+			s, rest, err := beancountUnquote(header)
+			if err != nil {
+				return err
+			}
+			strs = append(strs, s)
+			header = rest
+		case '#', '^':
+			// Tags and links don't carry over
+			end := strings.IndexAny(header, " \t")
+			if end < 0 {
+				end = len(header)
+			}
+			header = header[end:]
+		default:
+			return fmt.Errorf("unexpected %q in transaction header", strings.Fields(header)[0])
+		}
+		header = strings.TrimSpace(header)
+	}
+
+	tx := journalTx{line: blk.line, date: date}
+	switch len(strs) {
+	case 0:
+	case 1:
+		tx.memo = strs[0]
+	case 2:
+		tx.memo = strings.Trim(strs[0]+" - "+strs[1], " -")
+	default:
+		return errors.New("transaction has more strings than a payee and a narration")
+	}
+
+	for _, l := range blk.body {
+		switch {
+		case strings.HasPrefix(l.text, ";"):
+		case isMetaKey(l.text):
+			meta, err := beancountMeta([]bodyLine{l})
+			if err != nil {
+				return err
+			}
+			if at, ok := meta["time"]; ok {
+				tx.date = parseTime(date, at)
+			}
+		default:
+			lg, err := beancountLeg(l)
+			if err != nil {
+				return err
+			}
+			tx.legs = append(tx.legs, lg)
+		}
+	}
+
+	return r.transaction(tx)
+}
+
+// beancountLeg reads a posting: an account and, unless it is left to balance the
+// transaction, an amount and currency
+func beancountLeg(l bodyLine) (leg, error) {
+	text := cutComment(l.text)
+	if strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!") {
+		text = strings.TrimSpace(text[1:])
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !beancountAccount(fields[0]) {
+		return leg{}, errorAt(l.line, "invalid posting %q", l.text)
+	}
+
+	lg := leg{line: l.line, account: fields[0]}
+	switch {
+	case len(fields) == 1:
+		return lg, nil
+	case strings.ContainsAny(text, "{@"):
+		return leg{}, errorAt(l.line, "costs and prices are not supported")
+	case len(fields) == 2:
+		return leg{}, errorAt(l.line, "amount %v has no currency", fields[1])
+	case len(fields) > 3:
+		return leg{}, errorAt(l.line, "unexpected %q after the amount", fields[3])
+	}
+
+	amount, err := parseAmount(fields[1], fields[2])
+	if err != nil {
+		return leg{}, errorAt(l.line, "%v", err)
+	}
+	lg.amount, lg.currency = amount, fields[2]
+
+	return lg, nil
+}
+
+// beancountMeta reads key: value metadata lines, unquoting string values
+func beancountMeta(body []bodyLine) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, l := range body {
+		if strings.HasPrefix(l.text, ";") {
+			continue
+		}
+		if !isMetaKey(l.text) {
+			return nil, errorAt(l.line, "expected metadata, found %q", l.text)
+		}
+
+		key, value, _ := strings.Cut(l.text, ":")
+		value = strings.TrimSpace(cutComment(value))
+		if strings.HasPrefix(value, `"`) {
+			s, rest, err := beancountUnquote(value)
+			if err != nil || rest != "" {
+				return nil, errorAt(l.line, "invalid string %v", value)
+			}
+			value = s
+		}
+		meta[key] = value
+	}
+	return meta, nil
+}
+
+// beancountUnquote reads the string s starts with and returns what follows it
+func beancountUnquote(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", errors.New("string has no closing quote")
+}
+
+func beancountAccount(name string) bool {
+	for _, root := range beancountRoots {
+		if strings.HasPrefix(name, root) && len(name) > len(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMetaKey reports whether a line starts with a metadata key, which unlike an account
+// starts with a lower case letter
+func isMetaKey(text string) bool {
+	key, _, ok := strings.Cut(text, ":")
+	if !ok || key == "" || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+	return strings.Trim(key, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") == ""
+}
@@ -0,0 +1,217 @@
+package plaintext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const ledgerDate = "2006/01/02"
+
+type ledger struct {
+	w io.Writer
+}
+
+func (l *ledger) open(date time.Time, account string, currencies []string, name string) error {
+	_, err := fmt.Fprintf(l.w, "account %v\n", account)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		fmt.Fprintf(l.w, "    note %v\n", oneLine(name))
+	}
+	fmt.Fprintf(l.w, "    ; opened: %v\n", date.UTC().Format(ledgerDate))
+	if len(currencies) > 0 {
+		fmt.Fprintf(l.w, "    ; currencies: %v\n", strings.Join(currencies, ","))
+	}
+	_, err = fmt.Fprintln(l.w)
+	return err
+}
+
+func (l *ledger) transaction(e entry) error {
+	header := strings.TrimSpace(e.date.UTC().Format(ledgerDate) + " * " + oneLine(e.memo))
+	_, err := fmt.Fprintln(l.w, header)
+	if err != nil {
+		return err
+	}
+	if len(e.tags) > 0 {
+		fmt.Fprintf(l.w, "    ; :%v:\n", strings.Join(e.tags, ":"))
+	}
+	for _, link := range e.links {
+		kind, id, _ := strings.Cut(link, "-")
+		fmt.Fprintf(l.w, "    ; %v: %v\n", kind, id)
+	}
+	for _, kv := range e.meta {
+		fmt.Fprintf(l.w, "    ; %v: %v\n", kv[0], oneLine(kv[1]))
+	}
+
+	sign, other := "", "-"
+	if !e.credit {
+		sign, other = "-", ""
+	}
+	fmt.Fprintf(l.w, "    %-60v  %v%v %v\n", e.account, sign, e.amount, e.currency)
+	_, err = fmt.Fprintf(l.w, "    %-60v  %v%v %v\n\n", ExternalAccount, other, e.amount, e.currency)
+	return err
+}
+
+// note writes a comment, since ledger-cli has no directive for an event that moved no money
+func (l *ledger) note(date time.Time, account string, text string) error {
+	_, err := fmt.Fprintf(l.w, "; %v %v %v\n\n", date.UTC().Format(ledgerDate), account, oneLine(text))
+	return err
+}
+
+// ledgerDates are the date formats ledger-cli reads
+var ledgerDates = []string{ledgerDate, "2006-01-02", "2006.01.02"}
+
+// directive reads one ledger-cli directive. Accounts and transactions are read, commodity
+// and price declarations are skipped, and anything else, such as automated or periodic
+// transactions, is refused.
+func (l *ledger) directive(blk block, r *reader) error {
+	fields := strings.Fields(ledgerComment(blk.text))
+	if len(fields) == 0 {
+		// Only a comment, behind whitespace that doesn't count as an indent
+		return nil
+	}
+
+	first := fields[0]
+	switch first {
+	case "account":
+		return l.readAccount(blk, r)
+	case "commodity", "P":
+		return nil
+	}
+
+	date, ok := parseLedgerDate(first)
+	if !ok {
+		return fmt.Errorf("%q directives are not supported", first)
+	}
+
+	return l.readTransaction(date, blk, r)
+}
+
+// readAccount reads an account declaration. Its note names the account, and an opened
+// comment, as the exporter writes, dates it.
+func (l *ledger) readAccount(blk block, r *reader) error {
+	name := ledgerComment(strings.TrimPrefix(blk.text, "account"))
+	if name == "" {
+		return errors.New("account has no name")
+	}
+
+	var title string
+	var opened time.Time
+	for _, bl := range blk.body {
+		switch {
+		case strings.HasPrefix(bl.text, "note "):
+			title = strings.TrimSpace(strings.TrimPrefix(bl.text, "note"))
+		case strings.HasPrefix(bl.text, ";"):
+			key, value, _ := strings.Cut(strings.TrimSpace(bl.text[1:]), ":")
+			if key == "opened" {
+				opened, _ = parseLedgerDate(strings.TrimSpace(value))
+			}
+		}
+	}
+
+	return r.open(opened, name, nil, title)
+}
+
+// readTransaction reads a transaction, whose payee is its memo, and its postings
+func (l *ledger) readTransaction(date time.Time, blk block, r *reader) error {
+	header := ledgerComment(blk.text)
+	header = strings.TrimSpace(strings.TrimPrefix(header, strings.Fields(header)[0]))
+	if strings.HasPrefix(header, "*") || strings.HasPrefix(header, "!") {
+		header = strings.TrimSpace(header[1:])
+	}
+	if strings.HasPrefix(header, "(") {
+		end := strings.Index(header, ")")
+		if end < 0 {
+			return errors.New("transaction code has no closing parenthesis")
+		}
+		header = strings.TrimSpace(header[end+1:])
+	}
+
+	tx := journalTx{line: blk.line, date: date, memo: header}
+	for _, bl := range blk.body {
+		if strings.HasPrefix(bl.text, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(bl.text[1:]), ":")
+			if key == "time" {
+				tx.date = parseTime(date, strings.TrimSpace(value))
+			}
+			continue
+		}
+
+		lg, err := ledgerLeg(bl)
+		if err != nil {
+			return err
+		}
+		tx.legs = append(tx.legs, lg)
+	}
+
+	return r.transaction(tx)
+}
+
+// ledgerLeg reads a posting: an account, which may hold single spaces, and, after two
+// spaces or a tab, an amount with its currency code on either side
+func ledgerLeg(bl bodyLine) (leg, error) {
+	text := ledgerComment(bl.text)
+	if strings.HasPrefix(text, "* ") || strings.HasPrefix(text, "! ") {
+		text = strings.TrimSpace(text[1:])
+	}
+	if strings.HasPrefix(text, "(") || strings.HasPrefix(text, "[") {
+		return leg{}, errorAt(bl.line, "virtual postings are not supported")
+	}
+
+	account, amount := text, ""
+	if end := strings.Index(text, "  "); end >= 0 {
+		account, amount = text[:end], text[end:]
+	}
+	if end := strings.Index(account, "\t"); end >= 0 {
+		account, amount = text[:end], text[end:]
+	}
+	amount = strings.TrimSpace(amount)
+
+	lg := leg{line: bl.line, account: account}
+	if amount == "" {
+		return lg, nil
+	}
+	if strings.ContainsAny(amount, "@{=(") {
+		return leg{}, errorAt(bl.line, "prices, lot costs, balance assertions and expressions are not supported")
+	}
+
+	fields := strings.Fields(amount)
+	if len(fields) != 2 {
+		return leg{}, errorAt(bl.line, "amount %v needs a currency code", amount)
+	}
+
+	number, currency := fields[0], fields[1]
+	if !strings.ContainsAny(number[:1], "+-0123456789") {
+		number, currency = currency, number
+	}
+
+	i, err := parseAmount(number, currency)
+	if err != nil {
+		return leg{}, errorAt(bl.line, "%v", err)
+	}
+	lg.amount, lg.currency = i, currency
+
+	return lg, nil
+}
+
+func parseLedgerDate(s string) (time.Time, bool) {
+	// An auxiliary date after = doesn't change when the transaction posted
+	s, _, _ = strings.Cut(s, "=")
+	for _, layout := range ledgerDates {
+		date, err := time.Parse(layout, s)
+		if err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ledgerComment drops a trailing ; comment, which ledger-cli doesn't let free text escape
+func ledgerComment(s string) string {
+	s, _, _ = strings.Cut(s, ";")
+	return strings.TrimSpace(s)
+}
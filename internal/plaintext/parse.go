@@ -0,0 +1,470 @@
+package plaintext
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/partyscript/bledger/internal/importer"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// maxLineLength is the longest journal line Parse reads
+const maxLineLength = 1 << 20
+
+// Journal is what a plain-text journal holds for BLedger: an account for every asset
+// account it opens or posts to, keyed by its journal name, and a completed transaction
+// for every posting to one, oldest first. Everything else in the journal is outside
+// the ledger. Directives that can't be imported are listed in Errors by line.
+type Journal struct {
+	Accounts map[string]*model.Account
+	Postings []Posting
+	Errors   []importer.LineError
+}
+
+// Posting is a completed transaction on the account the journal calls Account. Its
+// AccountID is left for the importer to fill in once the account exists.
+type Posting struct {
+	Line        int
+	Account     string
+	Transaction model.Transaction
+}
+
+// IsAsset reports whether a journal account is one BLedger holds, rather than a
+// counterparty outside it
+func IsAsset(account string) bool {
+	return strings.HasPrefix(account, "Assets:")
+}
+
+// dialect reads the directives of one plain-text format
+type dialect interface {
+	directive(b block, r *reader) error
+}
+
+// block is a directive line and the indented lines under it
+type block struct {
+	line int
+	text string
+	body []bodyLine
+}
+
+type bodyLine struct {
+	line int
+	text string
+}
+
+// lineError is an error that belongs to a particular line of a block
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string {
+	return e.err.Error()
+}
+
+func errorAt(line int, format string, args ...interface{}) error {
+	return &lineError{line: line, err: fmt.Errorf(format, args...)}
+}
+
+// journalTx is a journal transaction before it is balanced
+type journalTx struct {
+	line int
+	date time.Time
+	memo string
+	legs []leg
+}
+
+// leg is one posting of a journal transaction. Amount is signed, in minor units, and
+// nil when the journal leaves it for the transaction to balance.
+type leg struct {
+	line     int
+	account  string
+	amount   *big.Int
+	currency string
+}
+
+// journalAccount is an asset account as the journal describes it
+type journalAccount struct {
+	name       string
+	title      string
+	opened     time.Time
+	declared   bool
+	currencies []string
+}
+
+// reader collects what a journal's directives say
+type reader struct {
+	format   Format
+	accounts map[string]*journalAccount
+	postings []Posting
+	errors   []importer.LineError
+}
+
+// Parse reads a Beancount or ledger-cli journal. It fails outright when the journal
+// can't be read or has more than maxPostings postings to asset accounts; directives
+// that can't be imported are reported on the journal.
+func Parse(format Format, r io.Reader, maxPostings int) (*Journal, error) {
+	var d dialect
+	var comments string
+	switch format {
+	case FormatBeancount:
+		d, comments = &beancount{}, ";*#"
+	case FormatLedger:
+		d, comments = &ledger{}, ";#%|*"
+	default:
+		return nil, fmt.Errorf("unknown journal format %v", format)
+	}
+
+	jr := &reader{
+		format:   format,
+		accounts: map[string]*journalAccount{},
+	}
+
+	blocks, err := jr.blocks(r, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range blocks {
+		err = d.directive(b, jr)
+
+		var le *lineError
+		switch {
+		case errors.As(err, &le):
+			jr.fail(le.line, le.err)
+		case err != nil:
+			jr.fail(b.line, err)
+		}
+
+		if len(jr.postings) > maxPostings {
+			return nil, fmt.Errorf("journal has more than %v postings", maxPostings)
+		}
+	}
+
+	return jr.journal()
+}
+
+// blocks splits a journal into directives. Lines starting with one of comments are
+// skipped, and a blank line ends the directive before it.
+func (jr *reader) blocks(r io.Reader, comments string) ([]block, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	var blocks []block
+	var current *block
+
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if n == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		// A line of nothing but whitespace, of any kind, is blank
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case trimmed == "":
+			current = nil
+		case text[0] == ' ' || text[0] == '\t':
+			if current != nil {
+				current.body = append(current.body, bodyLine{line: n, text: trimmed})
+			} else if !strings.ContainsRune(comments, rune(trimmed[0])) {
+				jr.fail(n, errors.New("indented line outside of a directive"))
+			}
+		case strings.ContainsRune(comments, rune(text[0])):
+		default:
+			blocks = append(blocks, block{line: n, text: text})
+			current = &blocks[len(blocks)-1]
+		}
+	}
+
+	return blocks, scanner.Err()
+}
+
+func (jr *reader) fail(line int, err error) {
+	jr.errors = append(jr.errors, importer.LineError{Line: line, Error: err.Error()})
+}
+
+// account returns the journal's asset account called name, adding it on first use
+func (jr *reader) account(name string) *journalAccount {
+	a, ok := jr.accounts[name]
+	if !ok {
+		a = &journalAccount{name: name}
+		jr.accounts[name] = a
+	}
+	return a
+}
+
+// open records an asset account. Currencies, when there are any, are the only ones
+// the journal may post to it.
+func (jr *reader) open(date time.Time, name string, currencies []string, title string) error {
+	if !IsAsset(name) {
+		return nil
+	}
+
+	for _, c := range currencies {
+		err := money.ValidateCurrency(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	a := jr.account(name)
+	if a.declared || !a.opened.IsZero() {
+		return fmt.Errorf("account %v is opened twice", name)
+	}
+	a.opened = date
+	a.title = title
+	if len(currencies) > 0 {
+		a.declared = true
+		a.currencies = currencies
+	}
+
+	return nil
+}
+
+// transaction balances a journal transaction, filling in the leg it leaves out, and
+// posts its legs on asset accounts
+func (jr *reader) transaction(tx journalTx) error {
+	if len(tx.legs) == 0 {
+		return errors.New("transaction has no postings")
+	}
+
+	var elided *leg
+	var order []string
+	sums := map[string]*big.Int{}
+	for i := range tx.legs {
+		l := &tx.legs[i]
+		if l.amount == nil {
+			if elided != nil {
+				return errorAt(l.line, "only one posting can leave out its amount")
+			}
+			elided = l
+			continue
+		}
+
+		sum, ok := sums[l.currency]
+		if !ok {
+			sum = new(big.Int)
+			sums[l.currency] = sum
+			order = append(order, l.currency)
+		}
+		sum.Add(sum, l.amount)
+	}
+
+	legs := tx.legs
+	for _, currency := range order {
+		sum := sums[currency]
+		if sum.Sign() == 0 {
+			continue
+		}
+		if elided == nil {
+			return fmt.Errorf("transaction doesn't balance, %v %v left over", signedMajor(sum, currency), currency)
+		}
+		legs = append(legs, leg{line: elided.line, account: elided.account, amount: new(big.Int).Neg(sum), currency: currency})
+	}
+
+	memo := tx.memo
+	if memo == "" {
+		memo = fmt.Sprintf("imported from %v line %v", jr.format, tx.line)
+	}
+
+	// Asset legs are checked before any is posted, so a transaction is taken whole or not at all
+	var posted []leg
+	for _, l := range legs {
+		if l.amount == nil || l.amount.Sign() == 0 || !IsAsset(l.account) {
+			continue
+		}
+
+		a, ok := jr.accounts[l.account]
+		if ok && a.declared && !holds(a.currencies, l.currency) {
+			return errorAt(l.line, "account %v doesn't hold %v", l.account, l.currency)
+		}
+		posted = append(posted, l)
+	}
+
+	postings := make([]Posting, 0, len(posted))
+	for _, l := range posted {
+		p, err := posting(tx, l, memo)
+		if err != nil {
+			return err
+		}
+		postings = append(postings, p)
+	}
+
+	for _, l := range posted {
+		a := jr.account(l.account)
+		if !holds(a.currencies, l.currency) {
+			a.currencies = append(a.currencies, l.currency)
+		}
+	}
+	jr.postings = append(jr.postings, postings...)
+
+	return nil
+}
+
+// posting is the completed transaction for a leg on an asset account
+func posting(tx journalTx, l leg, memo string) (Posting, error) {
+	direction := model.TransactionDirectionCredit
+	if l.amount.Sign() < 0 {
+		direction = model.TransactionDirectionDebit
+	}
+
+	amount, err := money.ParseAmount(new(big.Int).Abs(l.amount).String())
+	if err != nil {
+		return Posting{}, errorAt(l.line, "%v", err)
+	}
+
+	amtToStore, err := model.TransactionMoney{Amount: amount, Currency: l.currency}.Stored()
+	if err != nil {
+		return Posting{}, err
+	}
+
+	at := tx.date
+	t := model.Transaction{
+		Money:       amtToStore,
+		Memo:        memo,
+		Direction:   direction,
+		CompletedAt: &at,
+	}
+	t.CreatedAt = at
+
+	return Posting{Line: l.line, Account: l.account, Transaction: t}, nil
+}
+
+// journal turns what was read into accounts and date ordered postings
+func (jr *reader) journal() (*Journal, error) {
+	sort.SliceStable(jr.postings, func(i, j int) bool {
+		return jr.postings[i].Transaction.CompletedAt.Before(*jr.postings[j].Transaction.CompletedAt)
+	})
+
+	sort.SliceStable(jr.errors, func(i, j int) bool { return jr.errors[i].Line < jr.errors[j].Line })
+
+	j := &Journal{
+		Accounts: make(map[string]*model.Account, len(jr.accounts)),
+		Postings: jr.postings,
+		Errors:   jr.errors,
+	}
+
+	// An account not opened, or opened late, exists from its first posting
+	first := map[string]time.Time{}
+	for _, p := range jr.postings {
+		if _, ok := first[p.Account]; !ok {
+			first[p.Account] = *p.Transaction.CompletedAt
+		}
+	}
+
+	for name, a := range jr.accounts {
+		createdAt := a.opened
+		if at, ok := first[name]; ok && (createdAt.IsZero() || at.Before(createdAt)) {
+			createdAt = at
+		}
+
+		bal := model.AccountBalances{}
+		for _, c := range a.currencies {
+			bal = append(bal, model.AccountMoney{Currency: c})
+		}
+		amtToStore, err := bal.Stored()
+		if err != nil {
+			return nil, err
+		}
+
+		title := a.title
+		if title == "" {
+			title = name
+		}
+
+		acct := &model.Account{
+			Name:        title,
+			Description: "imported from " + name,
+			Balance:     amtToStore,
+			Version:     1,
+		}
+		acct.CreatedAt = createdAt
+		j.Accounts[name] = acct
+	}
+
+	return j, nil
+}
+
+// parseAmount parses a signed decimal in major units into minor units
+func parseAmount(number string, currency string) (*big.Int, error) {
+	cur, ok := money.Lookup(currency)
+	if !ok {
+		return nil, fmt.Errorf("unknown currency %v", currency)
+	}
+
+	negative := strings.HasPrefix(number, "-")
+	digits := strings.ReplaceAll(strings.TrimLeft(number, "+-"), ",", "")
+
+	amount, err := money.ParseFormattedAmount(digits, cur.Exponent)
+	if err != nil {
+		return nil, err
+	}
+
+	i := amount.BigInt()
+	if negative {
+		i.Neg(i)
+	}
+	return i, nil
+}
+
+// signedMajor renders a signed amount in minor units in major units
+func signedMajor(i *big.Int, currency string) string {
+	amount, err := money.ParseAmount(new(big.Int).Abs(i).String())
+	if err != nil {
+		return i.String()
+	}
+
+	s := formatAmount(amount, currency)
+	if i.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// parseTime reads the exact time a transaction was posted from its metadata, as the
+// exporter writes it, keeping the journal date when it doesn't fall on that date
+func parseTime(date time.Time, value string) time.Time {
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || at.UTC().Format("2006-01-02") != date.Format("2006-01-02") {
+		return date
+	}
+	return at
+}
+
+func holds(currencies []string, currency string) bool {
+	for _, c := range currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// cutComment drops a trailing comment starting at the first ; outside of quotes
+func cutComment(s string) string {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return strings.TrimSpace(s[:i])
+			}
+		}
+	}
+	return s
+}
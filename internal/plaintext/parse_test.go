@@ -0,0 +1,257 @@
+package plaintext
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/importer"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedPosting struct {
+	account   string
+	direction model.TransactionDirection
+	amount    string
+	currency  string
+	memo      string
+	at        string
+}
+
+func postings(t *testing.T, j *Journal) []parsedPosting {
+	var out []parsedPosting
+	for _, p := range j.Postings {
+		var m model.TransactionMoney
+		assert.NoError(t, json.Unmarshal(p.Transaction.Money, &m))
+		out = append(out, parsedPosting{
+			account:   p.Account,
+			direction: p.Transaction.Direction,
+			amount:    m.Amount.String(),
+			currency:  m.Currency,
+			memo:      p.Transaction.Memo,
+			at:        p.Transaction.CompletedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return out
+}
+
+func balances(t *testing.T, a *model.Account) []string {
+	var bal model.AccountBalances
+	assert.NoError(t, json.Unmarshal(a.Balance, &bal))
+	return bal.Currencies()
+}
+
+func TestParseBeancount(t *testing.T) {
+	journal := `option "title" "Books"
+; a comment
+* an org-mode heading
+
+2023-01-01 open Assets:Bank:Checking USD
+  name: "Checking \"main\""
+2023-01-01 open Income:Salary
+2023-01-01 open Expenses:Food
+2023-01-01 commodity USD
+
+2023-01-05 * "Employer" "January pay" #pay ^payslip-1
+  Assets:Bank:Checking   1,000.00 USD
+  Income:Salary
+
+2023-01-03 txn "Lunch ; with tax"
+  time: "2023-01-03T12:30:00Z"
+  Assets:Bank:Checking  -12.5 USD ; card
+  Expenses:Food          12.50 USD
+
+2023-01-06 ! "Savings"
+  Assets:Bank:Checking  -100 USD
+  Assets:Bank:Savings    100 USD
+`
+	j, err := Parse(FormatBeancount, strings.NewReader(journal), 100)
+	assert.NoError(t, err)
+	assert.Empty(t, j.Errors)
+
+	assert.Len(t, j.Accounts, 2)
+	checking := j.Accounts["Assets:Bank:Checking"]
+	assert.Equal(t, `Checking "main"`, checking.Name)
+	assert.Equal(t, "imported from Assets:Bank:Checking", checking.Description)
+	assert.Equal(t, "2023-01-01", checking.CreatedAt.Format("2006-01-02"))
+	assert.Equal(t, []string{"USD"}, balances(t, checking))
+
+	savings := j.Accounts["Assets:Bank:Savings"]
+	assert.Equal(t, "Assets:Bank:Savings", savings.Name)
+	assert.Equal(t, "2023-01-06", savings.CreatedAt.Format("2006-01-02"))
+
+	assert.Equal(t, []parsedPosting{
+		{"Assets:Bank:Checking", model.TransactionDirectionDebit, "1250", "USD", "Lunch ; with tax", "2023-01-03T12:30:00Z"},
+		{"Assets:Bank:Checking", model.TransactionDirectionCredit, "100000", "USD", "Employer - January pay", "2023-01-05T00:00:00Z"},
+		{"Assets:Bank:Checking", model.TransactionDirectionDebit, "10000", "USD", "Savings", "2023-01-06T00:00:00Z"},
+		{"Assets:Bank:Savings", model.TransactionDirectionCredit, "10000", "USD", "Savings", "2023-01-06T00:00:00Z"},
+	}, postings(t, j))
+	assert.Equal(t, 17, j.Postings[0].Line)
+}
+
+func TestParseLedger(t *testing.T) {
+	journal := `; ledger journal
+account Assets:Bank Checking
+    note Checking
+    ; opened: 2022/12/31
+
+commodity USD
+
+P 2023/01/01 EUR 1.10 USD
+
+2023/01/05=2023/01/06 * (1042) Employer  ; payroll
+    Assets:Bank Checking      USD 1,000.00
+    Income:Salary
+
+2023-01-07 Groceries
+    ; time: 2023-01-07T18:00:00Z
+    Expenses:Food                 45.10 EUR
+    * Assets:Bank Checking       -45.10 EUR
+`
+	j, err := Parse(FormatLedger, strings.NewReader(journal), 100)
+	assert.NoError(t, err)
+	assert.Empty(t, j.Errors)
+
+	assert.Len(t, j.Accounts, 1)
+	checking := j.Accounts["Assets:Bank Checking"]
+	assert.Equal(t, "Checking", checking.Name)
+	assert.Equal(t, "2022-12-31", checking.CreatedAt.Format("2006-01-02"))
+	assert.Equal(t, []string{"USD", "EUR"}, balances(t, checking))
+
+	assert.Equal(t, []parsedPosting{
+		{"Assets:Bank Checking", model.TransactionDirectionCredit, "100000", "USD", "Employer", "2023-01-05T00:00:00Z"},
+		{"Assets:Bank Checking", model.TransactionDirectionDebit, "4510", "EUR", "Groceries", "2023-01-07T18:00:00Z"},
+	}, postings(t, j))
+}
+
+func TestParseErrors(t *testing.T) {
+	beancountJournal := `2023-01-01 open Assets:Checking USD
+include "other.beancount"
+2023-01-02 balance Assets:Checking 0 USD
+2023-01-02 pad Assets:Checking Equity:Opening
+
+2023-01-03 * "Unbalanced"
+  Assets:Checking  10.00 USD
+  Income:Salary   -9.00 USD
+
+2023-01-04 * "Wrong currency"
+  Assets:Checking  10.00 EUR
+  Income:Salary
+
+2023-01-05 * "Priced"
+  Assets:Checking  10.00 USD @ 1.1 EUR
+  Income:Salary
+
+2023-01-06 * "Too precise"
+  Assets:Checking  10.001 USD
+  Income:Salary
+
+2023-01-07 * "Two elided"
+  Assets:Checking
+  Income:Salary
+
+2023-01-08 * "Unknown currency"
+  Assets:Checking  1 XXY
+  Income:Salary
+`
+	j, err := Parse(FormatBeancount, strings.NewReader(beancountJournal), 100)
+	assert.NoError(t, err)
+	assert.Empty(t, j.Postings)
+	assert.Equal(t, []importer.LineError{
+		{Line: 2, Error: `"include" directives are not supported`},
+		{Line: 3, Error: `"balance" directives are not supported`},
+		{Line: 4, Error: `"pad" directives are not supported`},
+		{Line: 6, Error: "transaction doesn't balance, 1.00 USD left over"},
+		{Line: 11, Error: "account Assets:Checking doesn't hold EUR"},
+		{Line: 15, Error: "costs and prices are not supported"},
+		{Line: 19, Error: "amount 10.001 has more than 2 decimals"},
+		{Line: 24, Error: "only one posting can leave out its amount"},
+		{Line: 27, Error: "unknown currency XXY"},
+	}, j.Errors)
+
+	ledgerJournal := `= /Food/
+    (Budget:Food)  -1
+
+~ Monthly
+    Assets:Checking  100 USD
+    Income:Salary
+
+2023/01/01 Virtual
+    (Assets:Checking)  10 USD
+
+2023/01/02 Assertion
+    Assets:Checking  10 USD = 10 USD
+    Income:Salary
+
+2023/01/03 No currency
+    Assets:Checking  $10.00
+    Income:Salary
+
+    stray line
+`
+	j, err = Parse(FormatLedger, strings.NewReader(ledgerJournal), 100)
+	assert.NoError(t, err)
+	assert.Empty(t, j.Postings)
+	assert.Equal(t, []importer.LineError{
+		{Line: 1, Error: `"=" directives are not supported`},
+		{Line: 4, Error: `"~" directives are not supported`},
+		{Line: 9, Error: "virtual postings are not supported"},
+		{Line: 12, Error: "prices, lot costs, balance assertions and expressions are not supported"},
+		{Line: 16, Error: "amount $10.00 needs a currency code"},
+		{Line: 19, Error: "indented line outside of a directive"},
+	}, j.Errors)
+
+	// Lines of other whitespace are blank, and a comment behind it is still a comment
+	for _, format := range []Format{FormatBeancount, FormatLedger} {
+		for _, journal := range []string{"\t\v\n", "\v\n", "\f\n", "\u00a0\n", "  \u00a0\n", "\v; note\n"} {
+			j, err = Parse(format, strings.NewReader(journal), 100)
+			assert.NoError(t, err, "%q", journal)
+			assert.Empty(t, j.Errors, "%q", journal)
+			assert.Empty(t, j.Postings, "%q", journal)
+		}
+	}
+
+	_, err = Parse("gnucash", strings.NewReader(""), 100)
+	assert.Error(t, err)
+
+	_, err = Parse(FormatBeancount, strings.NewReader(`2023-01-01 * "Pay"
+  Assets:Checking  1 USD
+  Assets:Savings   1 USD
+  Income:Salary
+`), 1)
+	assert.EqualError(t, err, "journal has more than 1 postings")
+}
+
+func TestParseExport(t *testing.T) {
+	for _, format := range []Format{FormatBeancount, FormatLedger} {
+		var out bytes.Buffer
+
+		w, err := NewWriter(format, &out)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Account(testAccount()))
+		for _, tx := range testLedger() {
+			assert.NoError(t, w.Transaction(tx))
+		}
+		assert.NoError(t, w.Flush())
+
+		j, err := Parse(format, &out, 100)
+		assert.NoError(t, err, format)
+		assert.Empty(t, j.Errors, format)
+
+		acct := j.Accounts[AccountName("2Acct")]
+		if assert.NotNil(t, acct, format) {
+			assert.Equal(t, `Jane "JD" Doe`, acct.Name, format)
+			assert.Equal(t, "2023-05-01", acct.CreatedAt.Format("2006-01-02"), format)
+		}
+
+		assert.Equal(t, []parsedPosting{
+			{AccountName("2Acct"), model.TransactionDirectionCredit, "1234", "USD", "memo tx1", "2023-05-02T12:00:00Z"},
+			{AccountName("2Acct"), model.TransactionDirectionDebit, "1234", "USD", "memo tx2", "2023-05-03T12:00:00Z"},
+			{AccountName("2Acct"), model.TransactionDirectionCredit, "5", "USD", "memo tx4", "2023-05-04T12:00:00Z"},
+			{AccountName("2Acct"), model.TransactionDirectionDebit, "5", "USD", "Reversal of memo tx4", "2023-05-05T12:00:00Z"},
+		}, postings(t, j), format)
+	}
+}
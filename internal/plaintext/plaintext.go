@@ -0,0 +1,225 @@
+// Package plaintext renders the ledger in the plain-text accounting formats of
+// Beancount and ledger-cli. Every BLedger account is an asset account, and money that
+// enters or leaves the ledger is balanced against a single external equity account.
+package plaintext
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// Format is a plain-text accounting format
+type Format string
+
+const (
+	// FormatBeancount is Beancount's syntax
+	FormatBeancount Format = "beancount"
+	// FormatLedger is ledger-cli's journal syntax
+	FormatLedger Format = "ledger"
+)
+
+const (
+	// AccountPrefix is the parent of every exported BLedger account
+	AccountPrefix = "Assets:Bledger:Acct-"
+	// ExternalAccount is the counterpart of every posting
+	ExternalAccount = "Equity:Bledger:External"
+)
+
+// AccountName returns the plain-text account name of a BLedger account id
+func AccountName(id string) string {
+	return AccountPrefix + id
+}
+
+// syntax writes directives in one format
+type syntax interface {
+	open(date time.Time, account string, currencies []string, name string) error
+	transaction(e entry) error
+	note(date time.Time, account string, text string) error
+}
+
+// entry is one balanced movement between a BLedger account and the external account
+type entry struct {
+	date     time.Time
+	account  string
+	memo     string
+	amount   string
+	currency string
+	credit   bool
+	tags     []string
+	links    []string
+	meta     [][2]string
+}
+
+// Writer writes accounts and transactions as they are handed to it, so a whole ledger
+// never has to be held in memory. Accounts must be written before their transactions.
+type Writer struct {
+	w        *bufio.Writer
+	syntax   syntax
+	external bool
+}
+
+// NewWriter returns a Writer for format that writes to w
+func NewWriter(format Format, w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+
+	var s syntax
+	switch format {
+	case FormatBeancount:
+		s = &beancount{w: bw}
+	case FormatLedger:
+		s = &ledger{w: bw}
+	default:
+		return nil, fmt.Errorf("unknown export format %v", format)
+	}
+
+	return &Writer{w: bw, syntax: s}, nil
+}
+
+// Account opens an account. The external account is opened alongside the first one,
+// so accounts should be written oldest first.
+func (w *Writer) Account(a model.Account) error {
+	if !w.external {
+		err := w.syntax.open(a.CreatedAt, ExternalAccount, nil, "")
+		if err != nil {
+			return err
+		}
+		w.external = true
+	}
+
+	var bal model.AccountBalances
+	err := json.Unmarshal(a.Balance, &bal)
+	if err != nil {
+		return err
+	}
+
+	return w.syntax.open(a.CreatedAt, AccountName(a.ID), bal.Currencies(), a.Name)
+}
+
+// Transaction writes a completed transaction as a posting against the external account,
+// tagged when it is a reversal or refund, and a transaction that closed without posting
+// as a note on its account. Pending transactions haven't happened yet and are skipped.
+func (w *Writer) Transaction(t model.Transaction) error {
+	var txMoney model.TransactionMoney
+	err := json.Unmarshal(t.Money, &txMoney)
+	if err != nil {
+		return err
+	}
+
+	amount := formatAmount(txMoney.Amount, txMoney.Currency)
+	credit := t.Direction == model.TransactionDirectionCredit
+
+	switch t.State {
+	case model.TransactionStateCompleted, model.TransactionStateReversed:
+	case model.TransactionStateFailed, model.TransactionStateVoided, model.TransactionStateExpired:
+		text := fmt.Sprintf("%v %v %v %v", t.State, t.Direction, amount, txMoney.Currency)
+		if t.ErrorCode != "" {
+			text += " " + string(t.ErrorCode)
+		}
+		if t.ErrorReason != "" {
+			text += ": " + t.ErrorReason
+		}
+		return w.syntax.note(closedAt(t), AccountName(t.AccountID), fmt.Sprintf("%v (%v)", text, t.ID))
+	default:
+		return nil
+	}
+
+	e := entry{
+		date:     common.CompletedAt(t),
+		account:  AccountName(t.AccountID),
+		memo:     t.Memo,
+		amount:   amount,
+		currency: txMoney.Currency,
+		credit:   credit,
+		meta:     [][2]string{{"id", t.ID}, {"time", common.CompletedAt(t).UTC().Format(time.RFC3339Nano)}},
+	}
+
+	for _, link := range []struct{ kind, id string }{
+		{"transfer", t.TransferID},
+		{"journal", t.JournalEntryID},
+		{"conversion", t.ConversionID},
+		{"hold", t.HoldID},
+	} {
+		if link.id != "" {
+			e.links = append(e.links, link.kind+"-"+link.id)
+		}
+	}
+
+	switch {
+	case t.ReversesTransactionID != "":
+		e.tags = append(e.tags, "reversal")
+		e.meta = append(e.meta, [2]string{"reverses", t.ReversesTransactionID})
+	case t.RefundOfTransactionID != "":
+		e.tags = append(e.tags, "refund")
+		e.meta = append(e.meta, [2]string{"refund-of", t.RefundOfTransactionID})
+	}
+	if t.ReversedBy != "" {
+		e.meta = append(e.meta, [2]string{"reversed-by", t.ReversedBy})
+	}
+
+	err = w.syntax.transaction(e)
+	if err != nil || t.State != model.TransactionStateReversed {
+		return err
+	}
+
+	// A legacy in-place reversal undid the posting later, so it gets its own entry
+	reversedAt := common.ReversedAt(t)
+
+	return w.syntax.transaction(entry{
+		date:     reversedAt,
+		account:  e.account,
+		memo:     "Reversal of " + t.Memo,
+		amount:   amount,
+		currency: txMoney.Currency,
+		credit:   !credit,
+		tags:     []string{"reversal"},
+		links:    e.links,
+		meta:     [][2]string{{"reverses", t.ID}, {"time", reversedAt.UTC().Format(time.RFC3339Nano)}},
+	})
+}
+
+// Flush writes whatever is still buffered
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// formatAmount renders an amount in major units, or minor units for currencies
+// outside the registry
+func formatAmount(amount money.Amount, currency string) string {
+	cur, ok := money.Lookup(currency)
+	if !ok {
+		return amount.String()
+	}
+	return money.FormatAmount(amount, cur.Exponent)
+}
+
+// closedAt returns when a transaction that never posted was closed
+func closedAt(t model.Transaction) time.Time {
+	var at *time.Time
+	switch t.State {
+	case model.TransactionStateFailed:
+		at = t.FailedAt
+	case model.TransactionStateVoided:
+		at = t.VoidedAt
+	case model.TransactionStateExpired:
+		at = t.ExpiresAt
+	}
+
+	// Transactions failed on create were closed when they were made
+	if at == nil {
+		return t.CreatedAt
+	}
+	return *at
+}
+
+// oneLine keeps free text from breaking the line-based formats
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
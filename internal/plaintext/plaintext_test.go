@@ -0,0 +1,115 @@
+package plaintext
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func day(d int) *time.Time {
+	t := time.Date(2023, 5, d, 12, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func testAccount() model.Account {
+	a := model.Account{
+		ID:      "2Acct",
+		Name:    `Jane "JD" Doe`,
+		Balance: datatypes.JSON(`[{"amount":"0","currency":"USD"},{"amount":"0","currency":"EUR"}]`),
+	}
+	a.CreatedAt = *day(1)
+	return a
+}
+
+func testTx(id string, dir model.TransactionDirection, amt uint64, state model.TransactionState, completed *time.Time) model.Transaction {
+	b, _ := json.Marshal(model.TransactionMoney{Amount: money.NewAmount(amt), Currency: "USD"})
+	t := model.Transaction{
+		ID:          id,
+		AccountID:   "2Acct",
+		Money:       datatypes.JSON(b),
+		Direction:   dir,
+		State:       state,
+		Memo:        "memo " + id,
+		CompletedAt: completed,
+	}
+	t.CreatedAt = *day(1)
+	t.UpdatedAt = *day(1)
+	return t
+}
+
+func testLedger() []model.Transaction {
+	credit := testTx("tx1", model.TransactionDirectionCredit, 1234, model.TransactionStateCompleted, day(2))
+	credit.TransferID = "tr1"
+	credit.ReversedBy = "tx2"
+
+	reversal := testTx("tx2", model.TransactionDirectionDebit, 1234, model.TransactionStateCompleted, day(3))
+	reversal.ReversesTransactionID = "tx1"
+
+	failed := testTx("tx3", model.TransactionDirectionDebit, 50, model.TransactionStateFailed, nil)
+	failed.FailedAt = day(4)
+	failed.ErrorCode = model.TransactionFailureCodeDeclined
+	failed.ErrorReason = "card declined"
+
+	legacy := testTx("tx4", model.TransactionDirectionCredit, 5, model.TransactionStateReversed, day(4))
+	legacy.ReversedAt = day(5)
+
+	pending := testTx("tx5", model.TransactionDirectionCredit, 5, model.TransactionStatePending, nil)
+
+	return []model.Transaction{credit, reversal, failed, legacy, pending}
+}
+
+func export(t *testing.T, format Format) string {
+	var out bytes.Buffer
+
+	w, err := NewWriter(format, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Account(testAccount()))
+	for _, tx := range testLedger() {
+		assert.NoError(t, w.Transaction(tx))
+	}
+	assert.NoError(t, w.Flush())
+
+	return out.String()
+}
+
+func TestBeancount(t *testing.T) {
+	out := export(t, FormatBeancount)
+
+	assert.Contains(t, out, "2023-05-01 open Equity:Bledger:External\n")
+	assert.Contains(t, out, "2023-05-01 open Assets:Bledger:Acct-2Acct USD,EUR\n  name: \"Jane \\\"JD\\\" Doe\"\n")
+	assert.Contains(t, out, "2023-05-02 * \"memo tx1\" ^transfer-tr1\n"+
+		"  id: \"tx1\"\n"+
+		"  time: \"2023-05-02T12:00:00Z\"\n"+
+		"  reversed-by: \"tx2\"\n"+
+		"  Assets:Bledger:Acct-2Acct")
+	assert.Regexp(t, `Assets:Bledger:Acct-2Acct +12\.34 USD\n  Equity:Bledger:External +-12\.34 USD\n`, out)
+	assert.Contains(t, out, "2023-05-03 * \"memo tx2\" #reversal\n")
+	assert.Contains(t, out, "  reverses: \"tx1\"\n")
+	assert.Contains(t, out, "2023-05-04 note Assets:Bledger:Acct-2Acct \"FAILED DEBIT 0.50 USD DECLINED: card declined (tx3)\"\n")
+	assert.Contains(t, out, "2023-05-05 * \"Reversal of memo tx4\" #reversal\n")
+	assert.Regexp(t, `Reversal of memo tx4[^*]+Assets:Bledger:Acct-2Acct +-0\.05 USD`, out)
+	assert.NotContains(t, out, "tx5")
+}
+
+func TestLedger(t *testing.T) {
+	out := export(t, FormatLedger)
+
+	assert.Contains(t, out, "account Equity:Bledger:External\n")
+	assert.Contains(t, out, "account Assets:Bledger:Acct-2Acct\n    note Jane \"JD\" Doe\n    ; opened: 2023/05/01\n    ; currencies: USD,EUR\n")
+	assert.Contains(t, out, "2023/05/02 * memo tx1\n    ; transfer: tr1\n    ; id: tx1\n")
+	assert.Regexp(t, `Assets:Bledger:Acct-2Acct +-12\.34 USD\n    Equity:Bledger:External +12\.34 USD\n`, out)
+	assert.Contains(t, out, "2023/05/03 * memo tx2\n    ; :reversal:\n")
+	assert.Contains(t, out, "; 2023/05/04 Assets:Bledger:Acct-2Acct FAILED DEBIT 0.50 USD DECLINED: card declined (tx3)\n")
+	assert.NotContains(t, out, "tx5")
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter("gnucash", &bytes.Buffer{})
+	assert.Error(t, err)
+}
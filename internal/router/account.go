@@ -1,17 +1,28 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/camt"
 	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/controller"
 	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/partyscript/bledger/internal/statement"
 	"github.com/partyscript/bledger/pkg"
+	"github.com/segmentio/ksuid"
 )
 
 // RegisterAccountsRouter is a router register method that applies the routes to a router
 func (m *Manager) RegisterAccountsRouter(router *gin.RouterGroup) {
 	router.GET("/:id", m.GetAccount)
+	router.GET("/:id/balance", m.GetAccountBalance)
+	router.GET("/:id/balances/daily", m.GetDailyBalances)
+	router.GET("/:id/statement", m.GetStatement)
+	router.GET("/:id/transactions", m.ListAccountTransactions)
 	router.POST("/", m.CreateAccount)
 }
 
@@ -40,6 +51,7 @@ func (m *Manager) GetAccount(c *gin.Context) {
 
 	}
 
+	c.Header("ETag", common.ETag(acct.Version))
 	c.JSON(http.StatusOK, &acct)
 }
 
@@ -57,11 +69,11 @@ func (m *Manager) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	acct, err := m.Controller.Accounts.CreateAccount(acctReq)
+	acct, err := m.Controller.Accounts.CreateAccount(acctReq, common.IfMatch(c))
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
-				common.BLedgerBadRequestError,
+				common.SentinelFor(err, common.BLedgerBadRequestError),
 				pkg.APIVersion,
 			),
 		)
@@ -69,5 +81,320 @@ func (m *Manager) CreateAccount(c *gin.Context) {
 
 	}
 
+	c.Header("ETag", common.ETag(acct.Version))
 	c.JSON(http.StatusOK, &acct)
 }
+
+// GetAccountBalance is a router method that returns an account balance at a point in time
+func (m *Manager) GetAccountBalance(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	asOf := time.Now()
+	if q := c.Query("as_of"); q != "" {
+		asOf, err = time.Parse(time.RFC3339, q)
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError("as_of must be an RFC3339 timestamp",
+					common.BLedgerBadRequestError,
+					pkg.APIVersion,
+				),
+			)
+			return
+		}
+	}
+
+	bal, err := m.Controller.Accounts.GetBalanceAsOf(id, asOf)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &bal)
+}
+
+// GetDailyBalances is a router method that returns an account's end-of-day balances
+// over a range of local dates
+func (m *Manager) GetDailyBalances(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var q model.DailyBalancesRequest
+	err = c.ShouldBindQuery(&q)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	windows, err := common.DailyWindows(q.From, q.To, q.TZ)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	days, err := m.Controller.Accounts.GetDailyBalances(id, windows)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.DailyBalanceSeries{
+		AccountID: id,
+		From:      q.From,
+		To:        q.To,
+		TZ:        windows[0].Start.Location().String(),
+		Days:      days,
+	})
+}
+
+// GetStatement is a router method that streams an account statement for a period.
+// Once the first line is written the status is sent, so a failure after that can only
+// cut the statement short; it is recorded on the context for the request log.
+func (m *Manager) GetStatement(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var q model.StatementRequest
+	err = c.ShouldBindQuery(&q)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	start, end, err := common.DateRange(q.From, q.To, q.TZ)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	// The opening balances, the postings and any closing balances all come from
+	// one snapshot, so the statement always adds up
+	err = m.Controller.Accounts.ReadStatement(func(r controller.StatementReader) error {
+		acct, opening, err := r.Opening(id, start)
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError(err.Error(),
+					common.BLedgerNotFoundError,
+					pkg.APIVersion,
+				),
+			)
+			return nil
+		}
+
+		if q.Format == camtStatementFormat {
+			return m.writeCAMTStatement(c, r, acct, opening, q, start, end)
+		}
+
+		format := statement.Format(q.Format)
+		if format == "" {
+			format = statement.FormatCSV
+		}
+
+		c.Header("Content-Type", statement.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%v-%v-%v.%v"`, id, q.From, q.To, format))
+		c.Status(http.StatusOK)
+
+		s, err := statement.New(format, c.Writer, statement.Header{
+			AccountID:   acct.ID,
+			AccountName: acct.Name,
+			Start:       start,
+			End:         end,
+			Opening:     opening,
+		})
+		if err == nil {
+			err = r.Entries(id, start, end, s.Add)
+		}
+		if err == nil {
+			err = s.Close()
+		}
+		return err
+	})
+	if err != nil {
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerInternalError,
+				pkg.APIVersion,
+			),
+		)
+	}
+}
+
+// camtStatementFormat selects an ISO 20022 camt.053 statement
+const camtStatementFormat = "camt053"
+
+// writeCAMTStatement streams a camt.053 document with one statement per currency the
+// account holds. Every currency is checked before the status is sent, so an account
+// holding an asset camt.053 can't express gets an error rather than a cut off document.
+// It returns only the errors that happen once the document has started.
+func (m *Manager) writeCAMTStatement(c *gin.Context, r controller.StatementReader, acct *model.Account, opening model.AccountBalances, q model.StatementRequest, start time.Time, end time.Time) error {
+	if len(opening) == 0 {
+		c.JSON(
+			common.WrapAPIError("account holds no currencies to make a camt.053 statement for",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return nil
+	}
+
+	for _, bal := range opening {
+		err := camt.CheckCurrency(bal.Currency)
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError(err.Error(),
+					common.BLedgerBadRequestError,
+					pkg.APIVersion,
+				),
+			)
+			return nil
+		}
+	}
+
+	// The balances just before the end of the period are the closing balances
+	_, closing, err := r.Opening(acct.ID, end)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return nil
+	}
+
+	c.Header("Content-Type", camt.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%v-%v-%v.xml"`, acct.ID, q.From, q.To))
+	c.Status(http.StatusOK)
+
+	w, err := camt.NewWriter(c.Writer, camt.Header{
+		MessageID:   ksuid.New().String(),
+		CreatedAt:   time.Now(),
+		AccountID:   acct.ID,
+		AccountName: acct.Name,
+		Start:       start,
+		End:         end,
+	})
+
+	for _, bal := range opening {
+		if err != nil {
+			break
+		}
+
+		var closingAmount money.Amount
+		if cb := closing.Find(bal.Currency); cb != nil {
+			closingAmount = cb.Amount
+		}
+
+		err = w.Statement(bal.Currency, bal.Amount, closingAmount)
+		if err == nil {
+			currency := bal.Currency
+			err = r.Entries(acct.ID, start, end, func(e statement.Entry) error {
+				if e.Currency != currency {
+					return nil
+				}
+				return w.Add(e)
+			})
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	return err
+}
+
+// ListAccountTransactions is a router method that returns a page of an account's transactions
+func (m *Manager) ListAccountTransactions(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var q model.ListTransactionsRequest
+	err = c.ShouldBindQuery(&q)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	page, err := m.Controller.Transactions.ListAccountTransactions(id, q)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &page)
+}
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// RegisterAdminRouter is a router register method that applies the routes to a router
+func (m *Manager) RegisterAdminRouter(router *gin.RouterGroup) {
+	router.GET("/verify", m.VerifyBalances)
+	router.POST("/verify/repair", m.RepairBalances)
+	router.GET("/export", m.ExportLedger)
+}
+
+// VerifyBalances is a router method that reports accounts whose stored balance
+// drifted from their transaction history
+func (m *Manager) VerifyBalances(c *gin.Context) {
+	m.verify(c, false)
+}
+
+// RepairBalances is a router method that reports and rewrites drifted balances
+func (m *Manager) RepairBalances(c *gin.Context) {
+	m.verify(c, true)
+}
+
+func (m *Manager) verify(c *gin.Context, repair bool) {
+	report, err := m.Controller.Verifier.Verify(repair)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerInternalError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// RegisterConversionsRouter is a router register method that applies the routes to a router
+func (m *Manager) RegisterConversionsRouter(router *gin.RouterGroup) {
+	router.GET("/:id", m.GetConversion)
+	router.POST("/", m.CreateConversion)
+}
+
+// GetConversion is a router method that returns a single conversion
+func (m *Manager) GetConversion(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	conversion, err := m.Controller.Conversions.GetConversion(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &conversion)
+}
+
+// CreateConversion is a router method that converts money between currencies at the provider rate
+func (m *Manager) CreateConversion(c *gin.Context) {
+	var cvReq model.CreateConversionRequest
+	err := c.ShouldBindJSON(&cvReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	conversion, err := m.Controller.Conversions.CreateConversion(cvReq, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversion)
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/plaintext"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// ExportLedger is a router method that streams every account and closed transaction as
+// a plain text accounting journal. As with statements, a failure once the body has
+// started can only cut the export short, so it is recorded on the context.
+func (m *Manager) ExportLedger(c *gin.Context) {
+	format := plaintext.Format(c.DefaultQuery("format", string(plaintext.FormatBeancount)))
+
+	w, err := plaintext.NewWriter(format, c.Writer)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="bledger.%v"`, format))
+	c.Status(http.StatusOK)
+
+	err = m.Controller.Export.StreamLedger(w.Account, w.Transaction)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
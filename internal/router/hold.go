@@ -0,0 +1,142 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// RegisterHoldsRouter is a router register method that applies the routes to a router
+func (m *Manager) RegisterHoldsRouter(router *gin.RouterGroup) {
+	router.GET("/:id", m.GetHold)
+	router.POST("/", m.CreateHold)
+	router.POST("/:id/capture", m.CaptureHold)
+	router.POST("/:id/void", m.VoidHold)
+}
+
+// GetHold is a router method that returns a single hold
+func (m *Manager) GetHold(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	hold, err := m.Controller.Holds.GetHold(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &hold)
+}
+
+// CreateHold is a router method that reserves funds on an account
+func (m *Manager) CreateHold(c *gin.Context) {
+	var hReq model.CreateHoldRequest
+	err := c.ShouldBindJSON(&hReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	hold, err := m.Controller.Holds.CreateHold(hReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+// CaptureHold is a router method that posts all or part of a hold
+func (m *Manager) CaptureHold(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var capReq model.CaptureHoldRequest
+	// An empty body captures the full amount
+	if c.Request.ContentLength != 0 {
+		err = c.ShouldBindJSON(&capReq)
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError(err.Error(),
+					common.BLedgerBadRequestError,
+					pkg.APIVersion,
+				),
+			)
+			return
+		}
+	}
+
+	hold, err := m.Controller.Holds.CaptureHold(id, capReq, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// VoidHold is a router method that releases a hold without posting it
+func (m *Manager) VoidHold(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	hold, err := m.Controller.Holds.VoidHold(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
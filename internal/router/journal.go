@@ -0,0 +1,71 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// RegisterJournalRouter is a router register method that applies the routes to a router
+func (m *Manager) RegisterJournalRouter(router *gin.RouterGroup) {
+	router.GET("/:id", m.GetJournalEntry)
+	router.POST("/", m.CreateJournalEntry)
+}
+
+// GetJournalEntry is a router method that returns a single journal entry and its postings
+func (m *Manager) GetJournalEntry(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	entry, err := m.Controller.Journal.GetJournalEntry(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &entry)
+}
+
+// CreateJournalEntry is a router method that posts a balanced multi-leg journal entry
+func (m *Manager) CreateJournalEntry(c *gin.Context) {
+	var jeReq model.CreateJournalEntryRequest
+	err := c.ShouldBindJSON(&jeReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	entry, err := m.Controller.Journal.CreateJournalEntry(jeReq, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
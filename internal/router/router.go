@@ -24,6 +24,11 @@ const (
 	versionRouterGroup     = "/v1"
 	transactionRouterGroup = "/transactions"
 	accountRouterGroup     = "/accounts"
+	transferRouterGroup    = "/transfers"
+	journalRouterGroup     = "/journal-entries"
+	conversionRouterGroup  = "/conversions"
+	holdRouterGroup        = "/holds"
+	adminRouterGroup       = "/admin"
 )
 
 // RegisterRouters is a router method to add all nested routers to the engine
@@ -36,6 +41,21 @@ func (m *Manager) RegisterRouters(router *gin.Engine) {
 
 	// Account Router Group
 	m.RegisterAccountsRouter(v1.Group(accountRouterGroup))
+
+	// Transfer Router Group
+	m.RegisterTransfersRouter(v1.Group(transferRouterGroup))
+
+	// Journal Entry Router Group
+	m.RegisterJournalRouter(v1.Group(journalRouterGroup))
+
+	// Conversion Router Group
+	m.RegisterConversionsRouter(v1.Group(conversionRouterGroup))
+
+	// Hold Router Group
+	m.RegisterHoldsRouter(v1.Group(holdRouterGroup))
+
+	// Admin Router Group, behind the admin token
+	m.RegisterAdminRouter(v1.Group(adminRouterGroup, middleware.AdminAuth(m.Controller.Cfg.Admin.Token)))
 }
 
 // NewRouterManager is a constructor that returns a new instance of RouterManager
@@ -68,14 +88,24 @@ func (m *Manager) InitRouter() error {
 	// Recover from panics
 	m.Router.Use(gin.Recovery())
 
-	// Cors
-	m.Router.Use(cors.Default())
+	// Cors, letting browsers send the admin token, actor and If-Match headers and read ETags
+	corsCfg := cors.DefaultConfig()
+	corsCfg.AllowAllOrigins = true
+	corsCfg.AddAllowHeaders("Authorization", common.ActorHeader, common.IfMatchHeader)
+	corsCfg.AddExposeHeaders("ETag")
+	m.Router.Use(cors.New(corsCfg))
 
 	// Idempotency
 	// m.Router.Use(middleware.Idempotency(m.IdemConfig, m.Cache))
 
+	// Custom request validators
+	err := common.RegisterValidators()
+	if err != nil {
+		return err
+	}
+
 	// No proxies
-	err := m.Router.SetTrustedProxies(nil)
+	err = m.Router.SetTrustedProxies(nil)
 	if err != nil {
 		return err
 	}
@@ -1,12 +1,15 @@
 package router
 
 import (
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/partyscript/bledger/internal/model"
 
 	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/importer"
 
 	"github.com/partyscript/bledger/pkg"
 )
@@ -14,13 +17,21 @@ import (
 // RegisterTransactionsRouter is a router register method that applies the routes to a router
 func (m *Manager) RegisterTransactionsRouter(router *gin.RouterGroup) {
 	router.GET("/:id", m.GetTransaction)
+	router.GET("/:id/history", m.GetTransactionHistory)
+	router.GET("/:id/versions", m.ListTransactionVersions)
 	router.POST("/", m.CreatePendingTransaction)
 	router.PUT("/:id", m.ExecutePendingTransaction)
 	router.POST("/immediate", m.CreateTransaction)
+	router.POST("/batch", m.CreateTransactionBatch)
+	router.POST("/import", m.ImportTransactions)
 	router.DELETE("/:id", m.ReverseTransaction)
+	router.POST("/:id/void", m.VoidTransaction)
+	router.POST("/:id/fail", m.FailTransaction)
+	router.POST("/:id/refunds", m.RefundTransaction)
+	router.GET("/:id/refunds", m.ListRefunds)
 }
 
-// ReverseTransaction is a router method that reverses a transaction
+// ReverseTransaction is a router method that reverses a transaction with a new linked transaction
 func (m *Manager) ReverseTransaction(c *gin.Context) {
 	id, err := common.CheckID(c)
 	if err != nil {
@@ -33,18 +44,19 @@ func (m *Manager) ReverseTransaction(c *gin.Context) {
 		return
 	}
 
-	err = m.Controller.Transactions.ReverseTransaction(id)
+	reversal, version, err := m.Controller.Transactions.ReverseTransaction(id, common.Actor(c), common.IfMatch(c))
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
-				common.BLedgerBadRequestError,
+				common.SentinelFor(err, common.BLedgerBadRequestError),
 				pkg.APIVersion,
 			),
 		)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "transaction reversed"})
+	c.Header("ETag", common.ETag(version))
+	c.JSON(http.StatusCreated, reversal)
 }
 
 // CreatePendingTransaction is a router method that creates a pending transaction
@@ -62,17 +74,18 @@ func (m *Manager) CreatePendingTransaction(c *gin.Context) {
 
 	}
 
-	transaction, err := m.Controller.Transactions.CreatePendingTransaction(txReq)
+	transaction, version, err := m.Controller.Transactions.CreatePendingTransaction(txReq, common.Actor(c), common.IfMatch(c))
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
-				common.BLedgerBadRequestError,
+				common.SentinelFor(err, common.BLedgerBadRequestError),
 				pkg.APIVersion,
 			),
 		)
 		return
 	}
 
+	c.Header("ETag", common.ETag(version))
 	c.JSON(http.StatusOK, &transaction)
 }
 
@@ -89,21 +102,23 @@ func (m *Manager) ExecutePendingTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := m.Controller.Transactions.ExecutePendingTransaction(id)
+	transaction, err := m.Controller.Transactions.ExecutePendingTransaction(id, common.Actor(c), common.IfMatch(c))
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
-				common.BLedgerBadRequestError,
+				common.SentinelFor(err, common.BLedgerBadRequestError),
 				pkg.APIVersion,
 			),
 		)
 		return
 	}
 
+	c.Header("ETag", common.ETag(transaction.Version))
 	c.JSON(http.StatusOK, &transaction)
 }
 
-// GetTransaction is a router method that returns a single transaction
+// GetTransaction is a router method that returns a single transaction, at its current
+// version or at the one asked for with ?version=N
 func (m *Manager) GetTransaction(c *gin.Context) {
 	id, err := common.CheckID(c)
 	if err != nil {
@@ -116,7 +131,24 @@ func (m *Manager) GetTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := m.Controller.Transactions.GetTransaction(id)
+	var q model.GetTransactionRequest
+	err = c.ShouldBindQuery(&q)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var transaction *model.Transaction
+	if q.Version != 0 {
+		transaction, err = m.Controller.Transactions.GetTransactionVersion(id, q.Version)
+	} else {
+		transaction, err = m.Controller.Transactions.GetTransaction(id)
+	}
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
@@ -128,9 +160,193 @@ func (m *Manager) GetTransaction(c *gin.Context) {
 
 	}
 
+	c.Header("ETag", common.ETag(transaction.Version))
 	c.JSON(http.StatusOK, &transaction)
 }
 
+// ListTransactionVersions is a router method that returns every version of a transaction
+func (m *Manager) ListTransactionVersions(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	versions, err := m.Controller.Transactions.ListTransactionVersions(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetTransactionHistory is a router method that returns a transaction's state changes
+func (m *Manager) GetTransactionHistory(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	events, err := m.Controller.Transactions.GetTransactionHistory(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// CreateTransactionBatch is a router method that posts a batch of immediate transactions
+func (m *Manager) CreateTransactionBatch(c *gin.Context) {
+	// A batch posts to many accounts, so there is no one version for If-Match to match
+	err := common.IfMatch(c).CheckUnsupported()
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerPreconditionFailedError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var batchReq model.CreateTransactionBatchRequest
+	err = c.ShouldBindJSON(&batchReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	batch, err := m.Controller.Transactions.CreateTransactionBatch(batchReq, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// ImportTransactions is a router method that imports transactions from an uploaded CSV
+// file, sent either as the raw request body or as the "file" field of a multipart form.
+// It answers with the import report: 200 for a dry run, 201 once committed and 400 when
+// a row failed and nothing was committed.
+func (m *Manager) ImportTransactions(c *gin.Context) {
+	// Like a batch, an import posts to many accounts and has no one version to match
+	err := common.IfMatch(c).CheckUnsupported()
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerPreconditionFailedError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	cols, err := importer.ParseColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	body := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError(err.Error(),
+					common.BLedgerBadRequestError,
+					pkg.APIVersion,
+				),
+			)
+			return
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(
+				common.WrapAPIError(err.Error(),
+					common.BLedgerBadRequestError,
+					pkg.APIVersion,
+				),
+			)
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	rows, err := importer.Parse(body, cols, m.Controller.Cfg.Batch.MaxImportRows)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := m.Controller.Transactions.ImportTransactions(rows, dryRun, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerInternalError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case report.Committed:
+		status = http.StatusCreated
+	case !dryRun:
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, report)
+}
+
 // CreateTransaction is a router method that creates a new transaction
 func (m *Manager) CreateTransaction(c *gin.Context) {
 	var transaction model.CreateTransactionRequest
@@ -145,16 +361,152 @@ func (m *Manager) CreateTransaction(c *gin.Context) {
 		return
 
 	}
-	newTx, err := m.Controller.Transactions.CreateTransaction(transaction)
+	newTx, version, err := m.Controller.Transactions.CreateTransaction(transaction, common.Actor(c), common.IfMatch(c))
 	if err != nil {
 		c.JSON(
 			common.WrapAPIError(err.Error(),
-				common.BLedgerBadRequestError,
+				common.SentinelFor(err, common.BLedgerBadRequestError),
 				pkg.APIVersion,
 			),
 		)
 		return
 
 	}
+	c.Header("ETag", common.ETag(version))
 	c.JSON(http.StatusCreated, newTx)
 }
+
+// VoidTransaction is a router method that cancels a pending transaction
+func (m *Manager) VoidTransaction(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	transaction, err := m.Controller.Transactions.VoidPendingTransaction(id, common.Actor(c), common.IfMatch(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.Header("ETag", common.ETag(transaction.Version))
+	c.JSON(http.StatusOK, &transaction)
+}
+
+// FailTransaction is a router method that fails a pending transaction with a reason code
+func (m *Manager) FailTransaction(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var fReq model.FailTransactionRequest
+	err = c.ShouldBindJSON(&fReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	transaction, err := m.Controller.Transactions.FailPendingTransaction(id, fReq, common.Actor(c), common.IfMatch(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.Header("ETag", common.ETag(transaction.Version))
+	c.JSON(http.StatusOK, &transaction)
+}
+
+// RefundTransaction is a router method that refunds part or all of a completed transaction
+func (m *Manager) RefundTransaction(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	var rfReq model.CreateRefundRequest
+	err = c.ShouldBindJSON(&rfReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	refund, version, err := m.Controller.Transactions.RefundTransaction(id, rfReq, common.Actor(c), common.IfMatch(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.SentinelFor(err, common.BLedgerBadRequestError),
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.Header("ETag", common.ETag(version))
+	c.JSON(http.StatusCreated, refund)
+}
+
+// ListRefunds is a router method that returns the refunds of a transaction
+func (m *Manager) ListRefunds(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	refunds, err := m.Controller.Transactions.ListRefunds(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, refunds)
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/partyscript/bledger/internal/common"
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/pkg"
+)
+
+// RegisterTransfersRouter is a router register method that applies the routes to a router
+func (m *Manager) RegisterTransfersRouter(router *gin.RouterGroup) {
+	router.GET("/:id", m.GetTransfer)
+	router.POST("/", m.CreateTransfer)
+}
+
+// GetTransfer is a router method that returns a single transfer
+func (m *Manager) GetTransfer(c *gin.Context) {
+	id, err := common.CheckID(c)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError("id not found on request",
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	transfer, err := m.Controller.Transfers.GetTransfer(id)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerNotFoundError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, &transfer)
+}
+
+// CreateTransfer is a router method that moves money between two accounts atomically
+func (m *Manager) CreateTransfer(c *gin.Context) {
+	var trReq model.CreateTransferRequest
+	err := c.ShouldBindJSON(&trReq)
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	transfer, err := m.Controller.Transfers.CreateTransfer(trReq, common.Actor(c))
+	if err != nil {
+		c.JSON(
+			common.WrapAPIError(err.Error(),
+				common.BLedgerBadRequestError,
+				pkg.APIVersion,
+			),
+		)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
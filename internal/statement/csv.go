@@ -0,0 +1,78 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+var csvColumns = []string{"type", "posted_at", "transaction_id", "memo", "currency", "credit", "debit", "balance"}
+
+type csvEncoder struct {
+	w   *csv.Writer
+	loc *time.Location
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) opening(h Header) error {
+	e.loc = h.Start.Location()
+
+	err := e.w.Write(csvColumns)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range h.Opening {
+		err = e.w.Write([]string{"opening", h.Start.Format(time.RFC3339), "", "", m.Currency, "", "", m.Amount.String()})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *csvEncoder) entry(en Entry, balance money.Amount) error {
+	credit, debit := en.Amount.String(), ""
+	if en.Direction == model.TransactionDirectionDebit {
+		credit, debit = "", en.Amount.String()
+	}
+
+	return e.w.Write([]string{
+		strings.ToLower(string(en.Direction)),
+		en.PostedAt.In(e.loc).Format(time.RFC3339),
+		en.TransactionID,
+		csvSafe(en.Memo),
+		en.Currency,
+		credit,
+		debit,
+		balance.String(),
+	})
+}
+
+func (e *csvEncoder) closing(h Header, totals []Total) error {
+	for _, t := range totals {
+		err := e.w.Write([]string{"closing", h.End.Format(time.RFC3339), "", "", t.Currency, t.Credits.String(), t.Debits.String(), t.Closing.String()})
+		if err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvSafe keeps a spreadsheet from evaluating free text as a formula
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
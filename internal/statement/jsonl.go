@@ -0,0 +1,85 @@
+package statement
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// jsonLine is one JSON Lines record. Type is opening, credit, debit or closing.
+type jsonLine struct {
+	Type          string        `json:"type"`
+	AccountID     string        `json:"account_id,omitempty"`
+	At            time.Time     `json:"at"`
+	TransactionID string        `json:"transaction_id,omitempty"`
+	Memo          string        `json:"memo,omitempty"`
+	Currency      string        `json:"currency"`
+	Amount        *money.Amount `json:"amount,omitempty"`
+	Credits       *money.Amount `json:"credits,omitempty"`
+	Debits        *money.Amount `json:"debits,omitempty"`
+	Balance       money.Amount  `json:"balance"`
+}
+
+type jsonlEncoder struct {
+	enc *json.Encoder
+	loc *time.Location
+}
+
+func newJSONLEncoder(w io.Writer) *jsonlEncoder {
+	return &jsonlEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *jsonlEncoder) opening(h Header) error {
+	e.loc = h.Start.Location()
+
+	for _, m := range h.Opening {
+		err := e.enc.Encode(jsonLine{
+			Type:      "opening",
+			AccountID: h.AccountID,
+			At:        h.Start,
+			Currency:  m.Currency,
+			Balance:   m.Amount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *jsonlEncoder) entry(en Entry, balance money.Amount) error {
+	amount := en.Amount
+	return e.enc.Encode(jsonLine{
+		Type:          strings.ToLower(string(en.Direction)),
+		At:            en.PostedAt.In(e.loc),
+		TransactionID: en.TransactionID,
+		Memo:          en.Memo,
+		Currency:      en.Currency,
+		Amount:        &amount,
+		Balance:       balance,
+	})
+}
+
+func (e *jsonlEncoder) closing(h Header, totals []Total) error {
+	for i := range totals {
+		t := totals[i]
+		err := e.enc.Encode(jsonLine{
+			Type:      "closing",
+			AccountID: h.AccountID,
+			At:        h.End,
+			Currency:  t.Currency,
+			Credits:   &t.Credits,
+			Debits:    &t.Debits,
+			Balance:   t.Closing,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
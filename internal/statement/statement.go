@@ -0,0 +1,170 @@
+// Package statement writes account statements as CSV, JSON Lines or plain text.
+// Entries are written as they are added, so a statement never has to fit in memory.
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// Format is a statement output format
+type Format string
+
+const (
+	// FormatCSV is a comma separated statement with one row per posting
+	FormatCSV Format = "csv"
+	// FormatJSONL is a JSON Lines statement with one object per posting
+	FormatJSONL Format = "jsonl"
+	// FormatText is a fixed-width plain text statement for reading or printing
+	FormatText Format = "txt"
+)
+
+// Header describes the account and period a statement covers. The period is the
+// half-open range [Start, End), and times are written in Start's location.
+type Header struct {
+	AccountID   string
+	AccountName string
+	Start       time.Time
+	End         time.Time
+	Opening     model.AccountBalances
+}
+
+// Entry is one posting on the statement
+type Entry struct {
+	PostedAt      time.Time
+	TransactionID string
+	Direction     model.TransactionDirection
+	Amount        money.Amount
+	Currency      string
+	Memo          string
+}
+
+// Total summarizes one currency over the statement period
+type Total struct {
+	Currency string
+	Opening  money.Amount
+	Credits  money.Amount
+	Debits   money.Amount
+	Closing  money.Amount
+}
+
+// encoder writes the parts of a statement in one format
+type encoder interface {
+	opening(h Header) error
+	entry(e Entry, balance money.Amount) error
+	closing(h Header, totals []Total) error
+}
+
+// Statement keeps the running balance per currency while entries are written
+type Statement struct {
+	w          *bufio.Writer
+	enc        encoder
+	header     Header
+	currencies []string
+	totals     map[string]*Total
+}
+
+// New writes the statement's header and opening balances to w and returns a
+// Statement to add entries to
+func New(format Format, w io.Writer, h Header) (*Statement, error) {
+	bw := bufio.NewWriter(w)
+
+	var enc encoder
+	switch format {
+	case FormatCSV:
+		enc = newCSVEncoder(bw)
+	case FormatJSONL:
+		enc = newJSONLEncoder(bw)
+	case FormatText:
+		enc = newTextEncoder(bw)
+	default:
+		return nil, fmt.Errorf("unknown statement format %v", format)
+	}
+
+	s := &Statement{
+		w:      bw,
+		enc:    enc,
+		header: h,
+		totals: map[string]*Total{},
+	}
+	for _, m := range h.Opening {
+		s.currencies = append(s.currencies, m.Currency)
+		s.totals[m.Currency] = &Total{Currency: m.Currency, Opening: m.Amount, Closing: m.Amount}
+	}
+
+	err := enc.opening(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ContentType returns the HTTP content type of a statement format
+func ContentType(format Format) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Add writes one posting along with the balance it leaves in its currency
+func (s *Statement) Add(e Entry) error {
+	t, ok := s.totals[e.Currency]
+	if !ok {
+		t = &Total{Currency: e.Currency}
+		s.totals[e.Currency] = t
+		s.currencies = append(s.currencies, e.Currency)
+	}
+
+	var err error
+	switch e.Direction {
+	case model.TransactionDirectionCredit:
+		t.Credits, err = t.Credits.Add(e.Amount)
+		if err != nil {
+			return err
+		}
+		t.Closing, err = t.Closing.Add(e.Amount)
+	case model.TransactionDirectionDebit:
+		t.Debits, err = t.Debits.Add(e.Amount)
+		if err != nil {
+			return err
+		}
+		t.Closing, err = t.Closing.Sub(e.Amount)
+	default:
+		return fmt.Errorf("unknown direction %v", e.Direction)
+	}
+	if err != nil {
+		return fmt.Errorf("statement debits more than it credits in %v", e.Currency)
+	}
+
+	return s.enc.entry(e, t.Closing)
+}
+
+// Totals returns the opening, credit, debit and closing totals per currency so far
+func (s *Statement) Totals() []Total {
+	totals := make([]Total, 0, len(s.currencies))
+	for _, c := range s.currencies {
+		totals = append(totals, *s.totals[c])
+	}
+	return totals
+}
+
+// Close writes the closing totals and flushes whatever is still buffered
+func (s *Statement) Close() error {
+	err := s.enc.closing(s.header, s.Totals())
+	if err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
@@ -0,0 +1,145 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/partyscript/bledger/internal/model"
+	"github.com/partyscript/bledger/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHeader(t *testing.T) Header {
+	ny, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	return Header{
+		AccountID:   "acct_1",
+		AccountName: "Customer",
+		Start:       time.Date(2023, 11, 1, 0, 0, 0, 0, ny),
+		End:         time.Date(2023, 12, 1, 0, 0, 0, 0, ny),
+		Opening:     model.AccountBalances{{Amount: money.NewAmount(1000), Currency: "USD"}},
+	}
+}
+
+func testEntries() []Entry {
+	return []Entry{
+		{
+			PostedAt:      time.Date(2023, 11, 2, 15, 0, 0, 0, time.UTC),
+			TransactionID: "tx_1",
+			Direction:     model.TransactionDirectionCredit,
+			Amount:        money.NewAmount(250),
+			Currency:      "USD",
+			Memo:          "payroll",
+		},
+		{
+			PostedAt:      time.Date(2023, 11, 3, 15, 0, 0, 0, time.UTC),
+			TransactionID: "tx_2",
+			Direction:     model.TransactionDirectionDebit,
+			Amount:        money.NewAmount(1200),
+			Currency:      "USD",
+			Memo:          "=rent",
+		},
+		{
+			PostedAt:      time.Date(2023, 11, 4, 15, 0, 0, 0, time.UTC),
+			TransactionID: "tx_3",
+			Direction:     model.TransactionDirectionCredit,
+			Amount:        money.NewAmount(5),
+			Currency:      "EUR",
+		},
+	}
+}
+
+func writeStatement(t *testing.T, format Format) (string, *Statement) {
+	var out bytes.Buffer
+
+	s, err := New(format, &out, testHeader(t))
+	assert.NoError(t, err)
+	for _, e := range testEntries() {
+		assert.NoError(t, s.Add(e))
+	}
+	assert.NoError(t, s.Close())
+
+	return out.String(), s
+}
+
+func TestStatementTotals(t *testing.T) {
+	_, s := writeStatement(t, FormatCSV)
+
+	assert.Equal(t, []Total{
+		{Currency: "USD", Opening: money.NewAmount(1000), Credits: money.NewAmount(250), Debits: money.NewAmount(1200), Closing: money.NewAmount(50)},
+		{Currency: "EUR", Credits: money.NewAmount(5), Closing: money.NewAmount(5)},
+	}, s.Totals())
+}
+
+func TestStatementOverdrawn(t *testing.T) {
+	var out bytes.Buffer
+
+	s, err := New(FormatCSV, &out, testHeader(t))
+	assert.NoError(t, err)
+
+	err = s.Add(Entry{Direction: model.TransactionDirectionDebit, Amount: money.NewAmount(1001), Currency: "USD"})
+	assert.Error(t, err)
+}
+
+func TestStatementCSV(t *testing.T) {
+	out, _ := writeStatement(t, FormatCSV)
+
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"type", "posted_at", "transaction_id", "memo", "currency", "credit", "debit", "balance"},
+		{"opening", "2023-11-01T00:00:00-04:00", "", "", "USD", "", "", "1000"},
+		{"credit", "2023-11-02T11:00:00-04:00", "tx_1", "payroll", "USD", "250", "", "1250"},
+		{"debit", "2023-11-03T11:00:00-04:00", "tx_2", "'=rent", "USD", "", "1200", "50"},
+		{"credit", "2023-11-04T11:00:00-04:00", "tx_3", "", "EUR", "5", "", "5"},
+		{"closing", "2023-12-01T00:00:00-05:00", "", "", "USD", "250", "1200", "50"},
+		{"closing", "2023-12-01T00:00:00-05:00", "", "", "EUR", "5", "0", "5"},
+	}, rows)
+}
+
+func TestStatementJSONL(t *testing.T) {
+	out, _ := writeStatement(t, FormatJSONL)
+
+	var lines []map[string]interface{}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "opening", lines[0]["type"])
+	assert.Equal(t, "acct_1", lines[0]["account_id"])
+	assert.Equal(t, "1000", lines[0]["balance"])
+	assert.Equal(t, "debit", lines[2]["type"])
+	assert.Equal(t, "1200", lines[2]["amount"])
+	assert.Equal(t, "50", lines[2]["balance"])
+	assert.Equal(t, "2023-11-03T11:00:00-04:00", lines[2]["at"])
+	assert.Equal(t, "closing", lines[4]["type"])
+	assert.Equal(t, "250", lines[4]["credits"])
+	assert.Equal(t, "1200", lines[4]["debits"])
+	assert.NotContains(t, lines[4], "amount")
+}
+
+func TestStatementText(t *testing.T) {
+	out, _ := writeStatement(t, FormatText)
+
+	assert.Contains(t, out, "Statement for account acct_1 (Customer)\n")
+	assert.Contains(t, out, "Period 2023-11-01 to 2023-11-30, America/New_York\n")
+	assert.Contains(t, out, "Opening balance               10.00 USD\n")
+	assert.Contains(t, out, "2023-11-03 11:00  tx_2")
+	assert.Contains(t, out, "USD  credits 2.50 USD  debits 12.00 USD  closing balance 0.50 USD\n")
+	assert.Contains(t, out, "EUR  credits 0.05 EUR  debits 0.00 EUR  closing balance 0.05 EUR\n")
+}
+
+func TestStatementUnknownFormat(t *testing.T) {
+	_, err := New("pdf", &bytes.Buffer{}, testHeader(t))
+	assert.Error(t, err)
+}
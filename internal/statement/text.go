@@ -0,0 +1,86 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/partyscript/bledger/internal/money"
+)
+
+// textRow lays out a posting: time, transaction id, direction, amount, balance, memo
+const textRow = "%-16s  %-27s  %-6s  %22s  %22s  %s\n"
+
+type textEncoder struct {
+	w   io.Writer
+	loc *time.Location
+}
+
+func newTextEncoder(w io.Writer) *textEncoder {
+	return &textEncoder{w: w}
+}
+
+func (e *textEncoder) opening(h Header) error {
+	e.loc = h.Start.Location()
+	last := h.End.Add(-time.Nanosecond)
+
+	_, err := fmt.Fprintf(e.w, "Statement for account %v", h.AccountID)
+	if err != nil {
+		return err
+	}
+	if h.AccountName != "" {
+		fmt.Fprintf(e.w, " (%v)", h.AccountName)
+	}
+	fmt.Fprintf(e.w, "\nPeriod %v to %v, %v\n\n", h.Start.Format("2006-01-02"), last.Format("2006-01-02"), e.loc)
+
+	for _, m := range h.Opening {
+		fmt.Fprintf(e.w, "Opening balance  %22s\n", formatMoney(m.Amount, m.Currency))
+	}
+
+	fmt.Fprintln(e.w)
+	_, err = fmt.Fprintf(e.w, textRow, "POSTED AT", "TRANSACTION", "TYPE", "AMOUNT", "BALANCE", "MEMO")
+	return err
+}
+
+func (e *textEncoder) entry(en Entry, balance money.Amount) error {
+	// Keep one posting per line whatever the memo holds
+	memo := strings.Join(strings.Fields(en.Memo), " ")
+
+	_, err := fmt.Fprintf(e.w, textRow,
+		en.PostedAt.In(e.loc).Format("2006-01-02 15:04"),
+		en.TransactionID,
+		en.Direction,
+		formatMoney(en.Amount, en.Currency),
+		formatMoney(balance, en.Currency),
+		memo,
+	)
+	return err
+}
+
+func (e *textEncoder) closing(h Header, totals []Total) error {
+	fmt.Fprintln(e.w)
+
+	for _, t := range totals {
+		_, err := fmt.Fprintf(e.w, "%v  credits %v  debits %v  closing balance %v\n",
+			t.Currency,
+			formatMoney(t.Credits, t.Currency),
+			formatMoney(t.Debits, t.Currency),
+			formatMoney(t.Closing, t.Currency),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatMoney renders an amount in major units, falling back to minor units for
+// currencies outside the registry
+func formatMoney(amount money.Amount, currency string) string {
+	if f := money.Format(amount, currency); f != "" {
+		return f
+	}
+	return fmt.Sprintf("%v %v", amount, currency)
+}